@@ -2,6 +2,8 @@ package agent
 
 import (
 	"fmt" 
+	"math/rand"
+	"slices"
 
 	"solitaire/deck"
 	"solitaire/game"
@@ -13,6 +15,8 @@ type Agent struct {
 	lowCards map[deck.Card]int
 	emptyStack int
 	strategy Strategy
+	rng *rand.Rand
+	onMove func(*game.Game, *Moves, int)
 }
 
 type InitializationError string 
@@ -27,6 +31,7 @@ func NewAgent(game *game.Game, strategy Strategy) (*Agent,error) {
 		lowCards: make(map[deck.Card]int), 
 		emptyStack: -1,
 		strategy: strategy,
+		rng: rand.New(rand.NewSource(rand.Int63())),
 	}
 
 	for i,stack := range game.HiddenStacks {
@@ -42,6 +47,16 @@ func NewAgent(game *game.Game, strategy Strategy) (*Agent,error) {
 	return &agent,nil
 }
 
+// Reseed the agent's random source, so that a game can be replayed exactly
+func (agent *Agent) Seed(seed int64) {
+	agent.rng = rand.New(rand.NewSource(seed))
+}
+
+// Call `fn` with every move the agent chooses, just before it is played
+func (agent *Agent) OnMove(fn func(game *game.Game, moves *Moves, moveID int)) {
+	agent.onMove = fn
+}
+
 func (agent *Agent) recomputeHighLowCards() {
 	// Clear previous values
 	for c := range agent.highCards {
@@ -63,8 +78,8 @@ func (agent *Agent) recomputeHighLowCards() {
 	}
 }
 
-func (agent *Agent) findTableauMoves() [][2]int {
-	moves := make([][2]int, 0, game.NStacks - 1)
+// Each find appends to `moves`, so a caller can pass a slice to reuse, emptied
+func (agent *Agent) findTableauMoves(moves [][2]int) [][2]int {
 
 	for highCard,src := range agent.highCards {
 		if highCard.Rank == deck.King {
@@ -80,16 +95,22 @@ func (agent *Agent) findTableauMoves() [][2]int {
 		}
 	}
 
+	// Map iteration order is random, and games should be reproducible from their seed
+	slices.SortFunc(moves, func(a, b [2]int) int {
+		if a[0] != b[0] {
+			return a[0] - b[0]
+		}
+		return a[1] - b[1]
+	})
 	return moves 
 }
 
-func (agent *Agent) findAvailMoves() []int {
+func (agent *Agent) findAvailMoves(moves []int) []int {
 	availCard,err := agent.game.PeekAvail()
-	moves := make([]int, 0, game.NStacks)
 	if err == nil {
 		for i := range game.NStacks {
-			card,peekErr := agent.game.PeekQueue(i)
-			if peekErr == nil { // nonempty stack, must be able to place card
+			if len(agent.game.VisibleQueues[i]) > 0 { // nonempty stack, must be able to place card
+				card,_ := agent.game.PeekQueue(i)
 				if deck.CanPlace(availCard, card) {
 					moves = append(moves, i)
 				} 
@@ -112,11 +133,10 @@ func (agent *Agent) findAvailMoves() []int {
 	return moves 
 }
 
-func (agent *Agent) findMovesToTop() []int {
-	moves := make([]int, 0, game.NStacks)
+func (agent *Agent) findMovesToTop(moves []int) []int {
 	for i := range game.NStacks {
-		card,peekErr := agent.game.PeekQueue(i)
-		if peekErr == nil {
+		if len(agent.game.VisibleQueues[i]) > 0 { // Checked first, since an error costs an allocation
+			card,_ := agent.game.PeekQueue(i)
 			canPush,_ := agent.game.CanPushSuit(card)
 			if canPush {
 				moves = append(moves, i)
@@ -126,14 +146,19 @@ func (agent *Agent) findMovesToTop() []int {
 	return moves 
 }
 
-func (agent *Agent) findMovesFromTop() [][2]int {
-	moves := make([][2]int, 0, game.NStacks)
+func (agent *Agent) findMovesFromTop(moves [][2]int) [][2]int {
 	for suitID := range deck.NSuits {
+		if agent.game.SuitStacks[suitID] < 2 { // Empty, or just the ace; saves building an error
+			continue
+		}
 		suitCard,err := agent.game.PeekSuit(suitID)
 		if err == nil && suitCard.Rank != deck.Ace {
 			for i := range game.NStacks {
-				card,peekErr := agent.game.PeekQueue(i)
-				if peekErr == nil && deck.CanPlace(suitCard, card) {
+				if len(agent.game.VisibleQueues[i]) == 0 {
+					continue
+				}
+				card,_ := agent.game.PeekQueue(i)
+				if deck.CanPlace(suitCard, card) {
 					moves = append(moves, [2]int{suitID, i})
 				}
 			}
@@ -144,11 +169,48 @@ func (agent *Agent) findMovesFromTop() [][2]int {
 
 func (agent *Agent) findMoves() Moves {
 	return Moves{
-		Tableau: agent.findTableauMoves(),
-		Avail: agent.findAvailMoves(),
-		ToTop: agent.findMovesToTop(),
-		FromTop: agent.findMovesFromTop(),
+		Tableau: agent.findTableauMoves(make([][2]int, 0, game.NStacks - 1)),
+		Avail: agent.findAvailMoves(make([]int, 0, game.NStacks)),
+		ToTop: agent.findMovesToTop(make([]int, 0, game.NStacks)),
+		FromTop: agent.findMovesFromTop(make([][2]int, 0, game.NStacks)),
+	}
+}
+
+// The moves an agent would consider in `game`
+func FindMoves(game *game.Game) Moves {
+	agent := Agent{
+		game: game,
+		highCards: make(map[deck.Card]int),
+		lowCards: make(map[deck.Card]int),
+	}
+	agent.recomputeHighLowCards()
+	return agent.findMoves()
+}
+
+// FindMoves over and over without allocating, e.g. for millions of steps of an RL env.
+// The zero value is ready to use.
+type MoveFinder struct {
+	agent Agent
+}
+
+// Like FindMoves, but into `moves`, reusing its slices: what was in them is overwritten
+func (f *MoveFinder) Find(game *game.Game, moves *Moves) {
+	if f.agent.highCards == nil {
+		f.agent.highCards = make(map[deck.Card]int)
+		f.agent.lowCards = make(map[deck.Card]int)
 	}
+	f.agent.game = game
+	f.agent.recomputeHighLowCards()
+	moves.Tableau = f.agent.findTableauMoves(moves.Tableau[:0])
+	moves.Avail = f.agent.findAvailMoves(moves.Avail[:0])
+	moves.ToTop = f.agent.findMovesToTop(moves.ToTop[:0])
+	moves.FromTop = f.agent.findMovesFromTop(moves.FromTop[:0])
+}
+
+// Play move `idx` of `moves` (as returned by FindMoves) in `game`
+func ExecuteMove(game *game.Game, moves Moves, idx int) {
+	agent := Agent{game: game}
+	agent.executeMove(moves, idx)
 }
 
 func (agent *Agent) PrintValidMoves() {
@@ -202,7 +264,10 @@ func (agent *Agent) Act(verbose bool) (movedCard bool) {
 	moves := agent.findMoves()
 	var moveID int = -1
 	if moves.len() > 0 {
-		moveID = agent.strategy.choose(agent.game, &moves)
+		moveID = agent.strategy.choose(agent.game, &moves, agent.rng)
+		if moveID == Abstain {
+			moveID = -1
+		}
 	} else {
 		switch agent.strategy.(type) {
 		case Manual:
@@ -215,6 +280,9 @@ func (agent *Agent) Act(verbose bool) (movedCard bool) {
 		fmt.Printf("Executing move with index %v\n", moveID)
 	}
 	
+	if agent.onMove != nil {
+		agent.onMove(agent.game, &moves, moveID)
+	}
 	agent.executeMove(moves, moveID)
 	movedCard = moveID != -1
 	return movedCard
@@ -8,12 +8,17 @@ import (
 	"solitaire/game"
 )
 
-func (agent *Agent) findAvailCards() []*deck.Card {
+// Cards in the Avail and Deck that will be on top of the Avail at some point, if we just
+// keep flipping: first those reachable in this pass (nThisPass of them), then those reachable
+// in the next one. The next pass is left out if the rules don't allow another.
+// Cards reachable in both passes may appear twice.
+func (agent *Agent) findAvailCards() (cards []*deck.Card, nThisPass int) {
 	faceUp := agent.game.Avail
 	faceDn := agent.game.Deck
+	nFlip := agent.game.Rules.NFlip
 
-	cap := int(math.Ceil(float64(len(faceUp) + len(faceDn)) / float64(game.NFLIP)))
-	cards := make([]*deck.Card, 0, cap)
+	cap := int(math.Ceil(float64(len(faceUp) + len(faceDn)) / float64(nFlip)))
+	cards = make([]*deck.Card, 0, cap)
 
 	if len(faceUp) > 0 {
 		// Append current faceup card
@@ -22,31 +27,36 @@ func (agent *Agent) findAvailCards() []*deck.Card {
 
 	if len(faceDn) > 0 {
 		// Get every third card from face-down pile
-		for i := 2; i < len(faceDn); i += game.NFLIP {
+		for i := nFlip - 1; i < len(faceDn); i += nFlip {
 			cards = append(cards, &faceDn[i])
 		}
 
-		if len(faceDn) % game.NFLIP != 0 {
+		if len(faceDn) % nFlip != 0 {
 			cards = append(cards, &faceDn[len(faceDn) - 1])
 		}
 	}
 
+	nThisPass = len(cards)
+	if !agent.game.CanRedeal() {
+		return cards,nThisPass
+	}
+
 	if len(faceUp) > 0 {
 		// Get every third card from face-up pile
-		for i := 2; i < len(faceUp) - 1; i += game.NFLIP {
+		for i := nFlip - 1; i < len(faceUp) - 1; i += nFlip {
 			// len(faceUp) - 1 so we skip the originally added card
 			cards = append(cards, &faceUp[i])
 		}
 
-		if len(faceUp) % game.NFLIP != 0 {
+		if len(faceUp) % nFlip != 0 {
 			// If nFaceUp not divisible by 3
 			if len(faceDn) > 0 { 
 				// and there are face-down cards, go back through face-down pile
-				for i := (game.NFLIP - 1) - (len(faceUp) % game.NFLIP); i < len(faceDn); i += game.NFLIP {
+				for i := (nFlip - 1) - (len(faceUp) % nFlip); i < len(faceDn); i += nFlip {
 					cards = append(cards, &faceDn[i])
 				}
 
-				if (len(faceDn) + len(faceUp)) % 3 != 0 && len(faceDn) % 3 != 0 {
+				if (len(faceDn) + len(faceUp)) % nFlip != 0 && len(faceDn) % nFlip != 0 {
 					cards = append(cards, &faceDn[len(faceDn) - 1])
 				}
 			}
@@ -54,11 +64,22 @@ func (agent *Agent) findAvailCards() []*deck.Card {
 		}
 	}
 
-	return cards
+	return cards,nThisPass
 }
 
 
 
+// findAvailCards for any game, e.g. for evaluating positions
+func AvailCards(g *game.Game) (cards []deck.Card, nThisPass int) {
+	agent := Agent{game: g}
+	ptrs,nThisPass := agent.findAvailCards()
+	cards = make([]deck.Card, len(ptrs))
+	for i,c := range ptrs {
+		cards[i] = *c
+	}
+	return cards,nThisPass
+}
+
 func TestFindAvailCards() {
 	fmt.Println("Hello world!")
 
@@ -80,7 +101,8 @@ func TestFindAvailCards() {
 	strat := NullStrategy{}
 	agent,_ := NewAgent(game, strat)
 
-	for _,cardPtr := range agent.findAvailCards() {
+	cards,_ := agent.findAvailCards()
+	for _,cardPtr := range cards {
 		fmt.Print(*cardPtr, ", ")
 	}
 	fmt.Print("\n")
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+
+	"solitaire/belief"
+	"solitaire/game"
+)
+
+// Somewhere between one-move greedy and ExpectimaxStrategy: follows lines of moves whose
+// outcome the player already knows, keeping the Width best positions by Eval after each
+// move, for up to Depth moves. Then plays the first move of the best line found.
+//
+// A move that turns up a card the player hasn't seen ends its line, since what comes next
+// depends on the card. So only flips of a Deck that has been through once are followed;
+// a flip of unseen cards is only ever a line of its own, from the current position.
+// FromTop moves are never played, as in ExpectimaxStrategy.
+type BeamStrategy struct {
+	Width int
+	Depth int
+	Eval EvalFunc // ProgressEval if nil
+}
+
+var DefaultBeamStrategy = BeamStrategy{Width: 8, Depth: 4}
+
+type beamLine struct {
+	g *game.Game
+	first int // Move index of the first move in the line, -1 for Flip
+	value float64
+	done bool // Turned up an unseen card, so can't be followed any further
+}
+
+func (strat BeamStrategy) choose(g *game.Game, moves *Moves, rng *rand.Rand) int {
+	eval := strat.Eval
+	if eval == nil {
+		eval = ProgressEval
+	}
+	// As in ExpectimaxStrategy, search a world that agrees with everything seen
+	world := belief.New(g.View()).Sample(rng, g.Rules)
+
+	extend := func(line beamLine, moves Moves, idx int) beamLine {
+		after := line.g.Clone()
+		ExecuteMove(after, moves, idx)
+		return beamLine{
+			g: after,
+			first: line.first,
+			value: eval(after),
+			done: unknownSlotCount(after) < unknownSlotCount(line.g),
+		}
+	}
+
+	// The first moves, in order with Flip last, so that ties go to the first move
+	var beam []beamLine
+	best := beamLine{first: -1, value: math.Inf(-1)}
+	for i := 0; i <= moves.len(); i++ {
+		idx := i
+		if i == moves.len() {
+			idx = -1
+		}
+		if !searchable(world, *moves, idx) {
+			continue
+		}
+		beam = append(beam, extend(beamLine{g: world, first: idx}, *moves, idx))
+	}
+
+	for depth := 1; len(beam) > 0; depth++ {
+		// Stable, so equal lines keep the order they were found in
+		slices.SortStableFunc(beam, func(a, b beamLine) int {
+			switch {
+			case a.value > b.value:
+				return -1
+			case a.value < b.value:
+				return 1
+			}
+			return 0
+		})
+		if beam[0].value > best.value {
+			best = beam[0]
+		}
+		if depth >= max(strat.Depth, 1) {
+			break
+		}
+
+		var next []beamLine
+		for _,line := range beam[:min(len(beam), max(strat.Width, 1))] {
+			if line.done || line.g.IsWon() {
+				continue
+			}
+			lineMoves := FindMoves(line.g)
+			for idx := -1; idx < lineMoves.len(); idx++ {
+				if !searchable(line.g, lineMoves, idx) || (idx == -1 && !line.g.DeckSeen()) {
+					continue
+				}
+				next = append(next, extend(line, lineMoves, idx))
+			}
+		}
+		beam = next
+	}
+	return best.first
+}
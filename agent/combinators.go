@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"math/rand"
+
+	"solitaire/game"
+)
+
+// What a strategy returns from choose when it has no opinion, e.g. a rule that doesn't
+// apply. Agent.Act flips if the strategy it was given abstains.
+const Abstain = -2
+
+// Asks each strategy in turn, and plays the first move one of them doesn't abstain on.
+// E.g. a few hand-written rules first, with a search to fall back on.
+type Chain []Strategy
+
+func (chain Chain) choose(g *game.Game, moves *Moves, rng *rand.Rand) int {
+	for _,strat := range chain {
+		if idx := strat.choose(g, moves, rng); idx != Abstain {
+			return idx
+		}
+	}
+	return Abstain
+}
+
+// Every strategy votes for a move, with its weight (1 for any past the end of Weights, so
+// all 1 if it's nil), and the move with the most votes is played. Ties go to the earliest
+// strategy's choice. Strategies that abstain don't vote, and if they all do, so does the
+// Vote.
+type Vote struct {
+	Strategies []Strategy
+	Weights []float64
+}
+
+func (vote Vote) choose(g *game.Game, moves *Moves, rng *rand.Rand) int {
+	tally := make(map[int]float64)
+	var order []int // Moves in the order they got their first vote
+	for i,strat := range vote.Strategies {
+		idx := strat.choose(g, moves, rng)
+		if idx == Abstain {
+			continue
+		}
+		if _,ok := tally[idx]; !ok {
+			order = append(order, idx)
+		}
+		if i < len(vote.Weights) {
+			tally[idx] += vote.Weights[i]
+		} else {
+			tally[idx]++
+		}
+	}
+
+	best := Abstain
+	for _,idx := range order {
+		if best == Abstain || tally[idx] > tally[best] {
+			best = idx
+		}
+	}
+	return best
+}
+
+// Any legal move (or Flip), uniformly at random
+type RandomStrategy struct{}
+
+func (strat RandomStrategy) choose(g *game.Game, moves *Moves, rng *rand.Rand) int {
+	return rng.Intn(moves.len() + 1) - 1
+}
+
+// Plays Strategy, except that with probability Epsilon it plays Random instead
+// (RandomStrategy if nil). If the one picked abstains, so does the mix.
+type EpsilonMix struct {
+	Strategy Strategy
+	Random Strategy
+	Epsilon float64
+}
+
+func (mix EpsilonMix) choose(g *game.Game, moves *Moves, rng *rand.Rand) int {
+	if rng.Float64() < mix.Epsilon {
+		if mix.Random == nil {
+			return RandomStrategy{}.choose(g, moves, rng)
+		}
+		return mix.Random.choose(g, moves, rng)
+	}
+	return mix.Strategy.choose(g, moves, rng)
+}
+
+// Plays like Strategy, but never takes a card off the foundation: Strategy is only shown
+// the other moves. For falling back on from a strategy that never plays FromTop either,
+// which would put the card straight back up.
+type NoFromTop struct {
+	Strategy Strategy
+}
+
+func (n NoFromTop) choose(g *game.Game, moves *Moves, rng *rand.Rand) int {
+	// FromTop moves come last, so the others keep their indices
+	without := *moves
+	without.FromTop = nil
+	return n.Strategy.choose(g, &without, rng)
+}
+
+// A Strategy from a plain function, for strategies built outside this package (see dsl).
+// Return a move index, -1 to flip, or Abstain.
+type StrategyFunc func(g *game.Game, moves *Moves, rng *rand.Rand) int
+
+func (fn StrategyFunc) choose(g *game.Game, moves *Moves, rng *rand.Rand) int {
+	return fn(g, moves, rng)
+}
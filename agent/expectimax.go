@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"math"
+	"math/rand"
+
+	"solitaire/belief"
+	"solitaire/deck"
+	"solitaire/game"
+)
+
+// Scores a position for a search, higher is better. Searches only ever hand it worlds
+// sampled from what the player has seen, so looking at the hidden cards doesn't cheat,
+// but it doesn't tell you anything either.
+type EvalFunc func(g *game.Game) float64
+
+// More cards on the foundation, fewer face down. The same as the untrained linear ValueNet.
+func ProgressEval(g *game.Game) float64 {
+	view := g.View()
+	return float64(view.NFoundation())/52 - float64(view.NHiddenTotal())/21
+}
+
+// Looks Depth moves ahead, where turning up a card the player hasn't seen (in the tableau,
+// or flipped from a Deck that hasn't been through once) is a chance node: every unseen card
+// is equally likely to come up, as far as the player knows. A move is worth the average
+// over those outcomes, and leaves are scored with Eval (ProgressEval if nil).
+//
+// A chance node with more than MaxOutcomes outcomes is estimated from MaxOutcomes random
+// ones instead. That's every flip of more than one unseen card, so make it big enough.
+//
+// FromTop moves are never played, like TDStrategy: without them no sequence of moves
+// comes back round to the same position, so the agent can't cycle.
+type ExpectimaxStrategy struct {
+	Depth int
+	MaxOutcomes int
+	Eval EvalFunc
+}
+
+var DefaultExpectimaxStrategy = ExpectimaxStrategy{Depth: 2, MaxOutcomes: 8}
+
+func (strat ExpectimaxStrategy) choose(g *game.Game, moves *Moves, rng *rand.Rand) int {
+	s := expectimax{strat: strat, rng: rng, eval: strat.Eval}
+	if s.eval == nil {
+		s.eval = ProgressEval
+	}
+	// Search a world that agrees with everything seen, rather than the real one
+	world := belief.New(g.View()).Sample(rng, g.Rules)
+
+	// Ties go to the first move, with Flip last
+	best, bestValue := -1, math.Inf(-1)
+	for i := 0; i <= moves.len(); i++ {
+		idx := i
+		if i == moves.len() {
+			idx = -1
+		}
+		if !searchable(world, *moves, idx) {
+			continue
+		}
+		v := s.moveValue(world, *moves, idx, max(strat.Depth, 1))
+		if v > bestValue {
+			best, bestValue = idx, v
+		}
+	}
+	return best
+}
+
+type expectimax struct {
+	strat ExpectimaxStrategy
+	rng *rand.Rand
+	eval EvalFunc
+}
+
+// Whether the search looks at move `idx` at all: no FromTop, and no Flip that does nothing
+func searchable(g *game.Game, moves Moves, idx int) bool {
+	if idx == -1 {
+		return len(g.Deck) > 0 || (len(g.Avail) > 0 && g.CanRedeal())
+	}
+	return moves.At(idx).Kind != FromTop
+}
+
+// Value of the position, with `depth` moves still to look at
+func (s *expectimax) value(g *game.Game, depth int) float64 {
+	if depth == 0 || g.IsWon() {
+		return s.eval(g)
+	}
+	moves := FindMoves(g)
+	best := math.Inf(-1)
+	for i := -1; i < moves.len(); i++ {
+		if searchable(g, moves, i) {
+			best = max(best, s.moveValue(g, moves, i, depth))
+		}
+	}
+	if math.IsInf(best, -1) {
+		return s.eval(g) // Stuck
+	}
+	return best
+}
+
+// Expected value of playing move `idx` in `g`
+func (s *expectimax) moveValue(g *game.Game, moves Moves, idx int, depth int) float64 {
+	after := g.Clone()
+	ExecuteMove(after, moves, idx)
+	pool := unknownSlots(g, after)
+	nRevealed := len(pool) - unknownSlotCount(after)
+	if nRevealed == 0 {
+		return s.value(after, depth - 1)
+	}
+
+	// The revealed cards come first in `pool`, and every card in `pool` is unseen, so an
+	// outcome is just a choice of which cards in `pool` go in the revealed slots
+	n := len(pool)
+	total := 0.0
+	if nRevealed == 1 && n <= s.strat.MaxOutcomes {
+		for k := range n {
+			outcome := after.Clone()
+			swapSlots(outcome, pool[0], pool[k])
+			total += s.value(outcome, depth - 1)
+		}
+		return total / float64(n)
+	}
+	nOutcomes := max(s.strat.MaxOutcomes, 1)
+	for range nOutcomes {
+		outcome := after.Clone()
+		for a := range nRevealed {
+			swapSlots(outcome, pool[a], pool[a + s.rng.Intn(n - a)])
+		}
+		total += s.value(outcome, depth - 1)
+	}
+	return total / float64(nOutcomes)
+}
+
+// A position holding a card, in a way that survives Clone
+type cardSlot struct {
+	kind byte // 'q'ueue front, 'h'idden, 'a'vail, 'd'eck
+	i, j int
+}
+
+func (slot cardSlot) in(g *game.Game) *deck.Card {
+	switch slot.kind {
+	case 'q':
+		return &g.VisibleQueues[slot.i][0]
+	case 'h':
+		return &g.HiddenStacks[slot.i][slot.j]
+	case 'a':
+		return &g.Avail[slot.j]
+	}
+	return &g.Deck[slot.j]
+}
+
+func swapSlots(g *game.Game, a, b cardSlot) {
+	pa, pb := a.in(g), b.in(g)
+	*pa, *pb = *pb, *pa
+}
+
+func unknownSlotCount(g *game.Game) int {
+	n := 0
+	for _,stack := range g.HiddenStacks {
+		n += len(stack)
+	}
+	if !g.DeckSeen() {
+		n += len(g.Deck)
+	}
+	return n
+}
+
+// The slots of `after` holding cards the player hadn't seen in `before`: first the ones
+// the move turned up, then the ones still unknown
+func unknownSlots(before *game.Game, after *game.Game) []cardSlot {
+	var slots []cardSlot
+	for i := range game.NStacks {
+		if len(after.HiddenStacks[i]) < len(before.HiddenStacks[i]) {
+			slots = append(slots, cardSlot{'q', i, 0})
+		}
+	}
+	if !before.DeckSeen() && len(before.Deck) > 0 {
+		for j := len(before.Avail); j < len(after.Avail); j++ {
+			slots = append(slots, cardSlot{'a', 0, j})
+		}
+	}
+	for i,stack := range after.HiddenStacks {
+		for j := range stack {
+			slots = append(slots, cardSlot{'h', i, j})
+		}
+	}
+	if !after.DeckSeen() {
+		for j := range after.Deck {
+			slots = append(slots, cardSlot{'d', 0, j})
+		}
+	}
+	return slots
+}
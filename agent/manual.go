@@ -2,6 +2,7 @@ package agent
 
 import (
 	"fmt"
+	"math/rand"
 	"strings"
 	"strconv"
 
@@ -10,7 +11,7 @@ import (
 )
 
 type Manual struct{}
-func (strat Manual) choose(game *game.Game, moves *Moves) int { 
+func (strat Manual) choose(game *game.Game, moves *Moves, rng *rand.Rand) int { 
 	game.Display(true)
 	fmt.Printf("Move options: %v\n", moves)
 	chosenMoves := strat.parseInput()
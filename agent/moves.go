@@ -74,4 +74,110 @@ func (moves Moves) index(otherMoves Moves) int {
 	}
 
 	return -1
-}
\ No newline at end of file
+}
+type MoveKind byte
+
+const (
+	Flip MoveKind = iota
+	Tableau // Whole visible queue from stack Src to stack Dst
+	AvailToTableau // Top of Avail to stack Dst
+	AvailToTop // Top of Avail to its suit stack
+	ToTop // Front of stack Src to its suit stack
+	FromTop // Top of suit stack Src to stack Dst
+	NMoveKinds = iota
+)
+
+var moveKindNames = [...]string{
+	Flip: "Flip",
+	Tableau: "Tableau",
+	AvailToTableau: "AvailToTableau",
+	AvailToTop: "AvailToTop",
+	ToTop: "ToTop",
+	FromTop: "FromTop",
+}
+
+func (kind MoveKind) String() string {
+	if int(kind) < len(moveKindNames) {
+		return moveKindNames[kind]
+	}
+	return fmt.Sprintf("Invalid MoveKind: %v", byte(kind))
+}
+
+// Kinds are written out by name, e.g. in game records
+func (kind MoveKind) MarshalText() ([]byte,error) {
+	return []byte(kind.String()), nil
+}
+
+func (kind *MoveKind) UnmarshalText(text []byte) error {
+	for k,name := range moveKindNames {
+		if name == string(text) {
+			*kind = MoveKind(k)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown move kind %q", text)
+}
+
+// A single move, independent of its index in some Moves. Unused fields are -1.
+type Move struct {
+	Kind MoveKind
+	Src int
+	Dst int
+}
+
+func (move Move) String() string {
+	switch move.Kind {
+	case Flip, AvailToTop:
+		return move.Kind.String()
+	case AvailToTableau:
+		return fmt.Sprintf("%v(%v)", move.Kind, move.Dst)
+	case ToTop:
+		return fmt.Sprintf("%v(%v)", move.Kind, move.Src)
+	}
+	return fmt.Sprintf("%v(%v,%v)", move.Kind, move.Src, move.Dst)
+}
+
+// The move with index `idx`, using the same ordering as Agent.executeMove (-1 is Flip)
+func (moves Moves) At(idx int) Move {
+	if idx == -1 {
+		return Move{Flip, -1, -1}
+	}
+	if idx < len(moves.Tableau) {
+		m := moves.Tableau[idx]
+		return Move{Tableau, m[0], m[1]}
+	}
+	idx -= len(moves.Tableau)
+	if idx < len(moves.Avail) {
+		if moves.Avail[idx] == -1 {
+			return Move{AvailToTop, -1, -1}
+		}
+		return Move{AvailToTableau, -1, moves.Avail[idx]}
+	}
+	idx -= len(moves.Avail)
+	if idx < len(moves.ToTop) {
+		return Move{ToTop, moves.ToTop[idx], -1}
+	}
+	idx -= len(moves.ToTop)
+	if idx < len(moves.FromTop) {
+		m := moves.FromTop[idx]
+		return Move{FromTop, m[0], m[1]}
+	}
+	panic(fmt.Sprintf("Move index %v out of range for %v moves!", idx, moves.len()))
+}
+
+// Index of `move` in `moves`, or -2 if it is not there. Flip is always there, at -1.
+func (moves Moves) IndexOf(move Move) int {
+	if move.Kind == Flip {
+		return -1
+	}
+	for i := range moves.len() {
+		if moves.At(i) == move {
+			return i
+		}
+	}
+	return -2
+}
+
+func (moves Moves) Len() int {
+	return moves.len()
+}
@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"math"
+	"math/rand"
+
+	"solitaire/deck"
+	"solitaire/game"
+)
+
+// Plans around the stock: knows (via findAvailCards) which waste cards it can get to in
+// this pass and the next, prefers moves that leave the tableau able to take them, and
+// doesn't flip past a card it can use. Only cards the player has seen are planned for,
+// i.e. the Avail, and the Deck once it has been through once.
+//
+// Each move gets a score: a base score for its kind, plus PlanWeight for every reachable
+// waste card the move lets us place (NextPassWeight for those only reachable next pass).
+// The best score is played, and ties go to the first move.
+type StockStrategy struct {
+	PlanWeight float64
+	NextPassWeight float64
+}
+
+var DefaultStockStrategy = StockStrategy{PlanWeight: 25, NextPassWeight: 0.5}
+
+// Cards we are planning to get from the stock, with how much each one counts
+type stockTarget struct {
+	card deck.Card
+	weight float64
+}
+
+func (strat StockStrategy) targets(g *game.Game) []stockTarget {
+	agent := Agent{game: g}
+	cards,nThisPass := agent.findAvailCards()
+
+	inAvail := make(map[deck.Card]bool, len(g.Avail))
+	for _,c := range g.Avail {
+		inAvail[c] = true
+	}
+
+	seen := make(map[deck.Card]bool, len(cards))
+	targets := make([]stockTarget, 0, len(cards))
+	for i,c := range cards {
+		if seen[*c] {
+			continue
+		}
+		seen[*c] = true
+
+		if !inAvail[*c] && !g.DeckSeen() {
+			continue // Not turned up yet, so we can't know what it is
+		}
+		weight := 1.0
+		if i >= nThisPass {
+			weight = strat.NextPassWeight
+		}
+		targets = append(targets, stockTarget{*c, weight})
+	}
+	return targets
+}
+
+// How much of the reachable stock has somewhere to go in `g`
+func placeable(g *game.Game, targets []stockTarget) float64 {
+	total := 0.0
+	for _,t := range targets {
+		if canPush,_ := g.CanPushSuit(t.card); canPush {
+			total += t.weight
+			continue
+		}
+		for i := range game.NStacks {
+			front,err := g.PeekQueue(i)
+			if (err == nil && deck.CanPlace(t.card, front)) || (err != nil && t.card.Rank == deck.King) {
+				total += t.weight
+				break
+			}
+		}
+	}
+	return total
+}
+
+// Base score for a move, before planning. math.Inf(-1) for moves never worth making.
+func stockBaseScore(g *game.Game, move Move) float64 {
+	switch move.Kind {
+	case ToTop, AvailToTop:
+		score := 20.
+		var card deck.Card
+		if move.Kind == ToTop {
+			card,_ = g.PeekQueue(move.Src)
+		} else {
+			card,_ = g.PeekAvail()
+		}
+		if safeToTop(g, card) {
+			score = 50
+		}
+		if move.Kind == ToTop && len(g.VisibleQueues[move.Src]) == 1 && len(g.HiddenStacks[move.Src]) > 0 {
+			score += 20 // Turns a card up too
+		}
+		return score
+	case Tableau:
+		nHidden := len(g.HiddenStacks[move.Src])
+		if nHidden > 0 {
+			return 60 + float64(nHidden) // Dig where most cards are hidden first
+		}
+		return 0 // Only ever worth it for the plan, e.g. to make room for a king
+	case AvailToTableau:
+		return 30
+	case FromTop:
+		return -5 // Only worth it for the plan, see choose
+	}
+	return 0 // Flip
+}
+
+// Nothing could ever need to go on `card` in the tableau any more: both cards of the
+// opposite colour one rank below are already on the foundation (or it's an ace or a two)
+func safeToTop(g *game.Game, card deck.Card) bool {
+	if card.Rank <= deck.Two {
+		return true
+	}
+	for suit,size := range g.SuitStacks {
+		if byte(suit) % 2 != card.Color() && size < int(card.Rank) {
+			return false
+		}
+	}
+	return true
+}
+
+func (strat StockStrategy) choose(g *game.Game, moves *Moves, rng *rand.Rand) int {
+	targets := strat.targets(g)
+	before := placeable(g, targets)
+
+	topPlaceable := func(g *game.Game) bool {
+		top,err := g.PeekAvail()
+		return err == nil && placeable(g, []stockTarget{{top, 1}}) > 0
+	}
+
+	best, bestScore := -1, math.Inf(-1)
+	for i := -1; i < moves.len(); i++ {
+		move := moves.At(i)
+		score := stockBaseScore(g, move)
+		if move.Kind == Flip {
+			// Flipping now buries the current top card, so don't if something wants it
+			if topPlaceable(g) {
+				score -= 100
+			}
+		} else {
+			after := g.Clone()
+			ExecuteMove(after, *moves, i)
+			// Taking a card off the foundation for a waste card we'll only reach later just
+			// gets it sent straight back up again. Only do it for the current top card.
+			if move.Kind == FromTop && (topPlaceable(g) || !topPlaceable(after)) {
+				continue
+			}
+			// For the same reason, don't send a card up if the top card could go on it
+			if move.Kind == ToTop && topPlaceable(g) && !topPlaceable(after) {
+				score = 0
+			}
+			// Playing a waste card removes it from the targets, so recount from scratch
+			gain := placeable(after, strat.targets(after)) - before
+			if move.Kind == AvailToTableau || move.Kind == AvailToTop {
+				gain += 1 // ... but it did find a home, which is what we wanted
+			}
+			score += strat.PlanWeight * gain
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
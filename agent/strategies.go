@@ -1,17 +1,17 @@
 package agent
 
 import (
-	// "fmt"
+	"fmt"
 	"math/rand"
 	"solitaire/game"
 )
 
 type Strategy interface {
-	choose(*game.Game, *Moves) int
+	choose(*game.Game, *Moves, *rand.Rand) int
 }
 
 type NullStrategy struct{}
-func (strat NullStrategy) choose(game *game.Game, moves *Moves) int { return 0 }
+func (strat NullStrategy) choose(game *game.Game, moves *Moves, rng *rand.Rand) int { return 0 }
 
 type ProbabilisticStrategy struct{
 	PFlip float32
@@ -21,7 +21,7 @@ type ProbabilisticStrategy struct{
 	PFromTop float32
 }
 
-func (strat ProbabilisticStrategy) choose(game *game.Game, moves *Moves) int {
+func (strat ProbabilisticStrategy) choose(game *game.Game, moves *Moves, rng *rand.Rand) int {
 	nTableau := len(moves.Tableau)
 	nAvail := len(moves.Avail)
 	nToTop := len(moves.ToTop)
@@ -41,16 +41,67 @@ func (strat ProbabilisticStrategy) choose(game *game.Game, moves *Moves) int {
 	mpToTop = mpToTop / pTot
 	// mpFromTop = mpFromTop / pTot // Unecessary, since unused, and all add to 1 now
 
-	r := rand.Float32()
+	r := rng.Float32()
 	if r < mpFlip {
 		return -1
 	} else if r < mpFlip + mpTableau {
-		return rand.Intn(nTableau)
+		return rng.Intn(nTableau)
 	} else if r < mpFlip + mpTableau + mpAvail {
-		return nTableau + rand.Intn(nAvail)
+		return nTableau + rng.Intn(nAvail)
 	} else if r < mpFlip + mpTableau + mpAvail + mpToTop {
-		return nTableau + nAvail + rand.Intn(nToTop)
+		return nTableau + nAvail + rng.Intn(nToTop)
 	} else {
-		return nTableau + nAvail + nToTop + rand.Intn(nFromTop)
+		return nTableau + nAvail + nToTop + rng.Intn(nFromTop)
 	}
-}
\ No newline at end of file
+}
+
+// A numeric strategy parameter, with the range an optimizer should search it over
+type Param struct {
+	Name string
+	Min float64
+	Max float64
+	Log bool // Search over log10(value) instead of value
+}
+
+// Strategies with numeric parameters that can be tuned automatically (see package tune)
+type Tunable interface {
+	Strategy
+	Params() []Param
+	Values() []float64
+	WithValues([]float64) Tunable
+}
+
+// The probabilities are re-normalized every turn, so only their ratios matter,
+// and these tend to be separated by orders of magnitude. Hence the log scale.
+func (strat ProbabilisticStrategy) Params() []Param {
+	return []Param{
+		{Name: "PFlip", Min: 1e-8, Max: 1e4, Log: true},
+		{Name: "PTableau", Min: 1e-8, Max: 1e4, Log: true},
+		{Name: "PAvail", Min: 1e-8, Max: 1e4, Log: true},
+		{Name: "PToTop", Min: 1e-8, Max: 1e4, Log: true},
+		{Name: "PFromTop", Min: 1e-8, Max: 1e4, Log: true},
+	}
+}
+
+func (strat ProbabilisticStrategy) Values() []float64 {
+	return []float64{
+		float64(strat.PFlip),
+		float64(strat.PTableau),
+		float64(strat.PAvail),
+		float64(strat.PToTop),
+		float64(strat.PFromTop),
+	}
+}
+
+func (strat ProbabilisticStrategy) WithValues(values []float64) Tunable {
+	if len(values) != 5 {
+		panic(fmt.Sprintf("ProbabilisticStrategy has 5 parameters, got %v values!", len(values)))
+	}
+	return ProbabilisticStrategy{
+		PFlip: float32(values[0]),
+		PTableau: float32(values[1]),
+		PAvail: float32(values[2]),
+		PToTop: float32(values[3]),
+		PFromTop: float32(values[4]),
+	}
+}
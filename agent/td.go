@@ -0,0 +1,250 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+
+	"solitaire/deck"
+	"solitaire/game"
+)
+
+// Hand-crafted position features for the learned value function, from what the player sees.
+// Bump ValueFeaturesVersion if they change, since saved weights depend on them.
+//
+// They are deliberately few, and each one only ever moves one way as the game is won.
+// Features that merely predict losing (passes through the Deck, cards left in the Deck,
+// height of the tallest hidden stack) fit the values well but make a bad greedy player,
+// since it refuses moves that change them. Features like "kings at the bottom of a stack"
+// or "empty stacks" are worse still: they end up valued so that the agent won't clear
+// the last kings off the tableau, and it never finishes a game.
+const ValueFeaturesVersion = 1
+const NValueFeatures = 7
+
+func ValueFeatures(view game.View) []float64 {
+	f := make([]float64, NValueFeatures)
+	f[0] = 1 // Bias
+
+	nFoundation := view.NFoundation()
+	f[1] = float64(nFoundation) / 52
+	f[2] = float64(view.NHiddenTotal()) / 21
+
+	minSuit := deck.SuitSize
+	for _,size := range view.SuitStacks {
+		minSuit = min(minSuit, size)
+	}
+	f[3] = float64(minSuit) / float64(deck.SuitSize)
+
+	// Winning gets much more likely towards the end, which a linear function can't see from f[1]
+	f[4] = f[1] * f[1]
+
+	// Aces and twos that have not turned up yet
+	var seenLow [deck.NSuits][2]bool
+	markSeen := func(cards []deck.Card) {
+		for _,c := range cards {
+			if c.Rank <= deck.Two {
+				seenLow[c.Suit][c.Rank] = true
+			}
+		}
+	}
+	for _,queue := range view.VisibleQueues {
+		markSeen(queue)
+	}
+	markSeen(view.Avail)
+	markSeen(view.Deck)
+	nUnseenLow := 0
+	for suit,size := range view.SuitStacks {
+		for rank := size; rank <= int(deck.Two); rank++ {
+			if !seenLow[suit][rank] {
+				nUnseenLow++
+			}
+		}
+	}
+	f[5] = float64(nUnseenLow) / 8
+
+	if nFoundation == 52 {
+		f[6] = 1
+	}
+	return f
+}
+
+// A linear value function, or with NHidden > 0 a network with one tanh hidden layer.
+// All parameters live in Weights:
+//
+//	linear:    w (NIn)
+//	two-layer: W1 (NHidden x NIn, row-major), w2 (NHidden), b2 (1)
+//
+// The bias of the first layer is feature 0, which is always 1.
+type ValueNet struct {
+	FeaturesVersion int
+	NIn int
+	NHidden int
+	Weights []float64
+}
+
+func NewValueNet(nHidden int, rng *rand.Rand) *ValueNet {
+	net := &ValueNet{FeaturesVersion: ValueFeaturesVersion, NIn: NValueFeatures, NHidden: nHidden}
+	if nHidden == 0 {
+		// Start from "more cards on the foundation, fewer face down", which already plays
+		// about as well as ProbabilisticStrategy, rather than from a net that prefers nothing
+		net.Weights = make([]float64, net.NIn)
+		net.Weights[1] = 1
+		net.Weights[2] = -1
+		return net
+	}
+	net.Weights = make([]float64, nHidden*net.NIn + nHidden + 1)
+	scale := 1 / math.Sqrt(float64(net.NIn))
+	for i := range nHidden * net.NIn {
+		net.Weights[i] = scale * rng.NormFloat64()
+	}
+	return net
+}
+
+func (net *ValueNet) hidden(x []float64, h []float64) {
+	for j := range net.NHidden {
+		sum := 0.0
+		row := net.Weights[j*net.NIn : (j+1)*net.NIn]
+		for i,xi := range x {
+			sum += row[i] * xi
+		}
+		h[j] = math.Tanh(sum)
+	}
+}
+
+func (net *ValueNet) Value(x []float64) float64 {
+	if net.NHidden == 0 {
+		v := 0.0
+		for i,xi := range x {
+			v += net.Weights[i] * xi
+		}
+		return v
+	}
+	h := make([]float64, net.NHidden)
+	net.hidden(x, h)
+	w2 := net.Weights[net.NHidden*net.NIn:]
+	v := w2[net.NHidden] // b2
+	for j,hj := range h {
+		v += w2[j] * hj
+	}
+	return v
+}
+
+// Value at `x`, and its gradient with respect to Weights written into `grad`
+func (net *ValueNet) ValueGrad(x []float64, grad []float64) float64 {
+	if net.NHidden == 0 {
+		copy(grad, x)
+		return net.Value(x)
+	}
+	h := make([]float64, net.NHidden)
+	net.hidden(x, h)
+	off := net.NHidden*net.NIn
+	w2 := net.Weights[off:]
+	v := w2[net.NHidden]
+	for j,hj := range h {
+		v += w2[j] * hj
+		grad[off + j] = hj
+		dh := w2[j] * (1 - hj*hj)
+		for i,xi := range x {
+			grad[j*net.NIn + i] = dh * xi
+		}
+	}
+	grad[off + net.NHidden] = 1
+	return v
+}
+
+func (net *ValueNet) Save(path string) error {
+	data,err := json.MarshalIndent(net, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func LoadValueNet(path string) (*ValueNet,error) {
+	data,err := os.ReadFile(path)
+	if err != nil {
+		return nil,err
+	}
+	net := &ValueNet{}
+	if err := json.Unmarshal(data, net); err != nil {
+		return nil,err
+	}
+	if net.FeaturesVersion != ValueFeaturesVersion || net.NIn != NValueFeatures {
+		return nil,fmt.Errorf("%v: weights are for features version %v with %v inputs, expected version %v with %v",
+			path, net.FeaturesVersion, net.NIn, ValueFeaturesVersion, NValueFeatures)
+	}
+	want := net.NIn
+	if net.NHidden > 0 {
+		want = net.NHidden*net.NIn + net.NHidden + 1
+	}
+	if len(net.Weights) != want {
+		return nil,fmt.Errorf("%v: expected %v weights, got %v", path, want, len(net.Weights))
+	}
+	return net,nil
+}
+
+// The view after a move, without anything the move revealed: a card turned up in the
+// tableau, or cards flipped from a Deck that has not been seen yet. Valuing what the move
+// would reveal would be cheating.
+func afterstateView(before *game.Game, after *game.Game) game.View {
+	view := after.View()
+	for i := range game.NStacks {
+		if len(after.HiddenStacks[i]) < len(before.HiddenStacks[i]) {
+			view.VisibleQueues[i] = view.VisibleQueues[i][:len(view.VisibleQueues[i]) - 1]
+		}
+	}
+	if !after.DeckSeen() && len(view.Avail) > len(before.Avail) {
+		view.NDeck += len(view.Avail) - len(before.Avail)
+		view.Avail = view.Avail[:len(before.Avail)]
+	}
+	return view
+}
+
+// Value of each move's afterstate, with Flip (index -1) last
+func AfterstateValues(g *game.Game, moves *Moves, net *ValueNet) []float64 {
+	values := make([]float64, moves.len() + 1)
+	for i := -1; i < moves.len(); i++ {
+		after := g.Clone()
+		ExecuteMove(after, *moves, i)
+		v := net.Value(ValueFeatures(afterstateView(g, after)))
+		if i == -1 {
+			values[moves.len()] = v
+		} else {
+			values[i] = v
+		}
+	}
+	return values
+}
+
+// One-ply lookahead with a learned value function: play the move whose afterstate has
+// the highest value, or with probability Epsilon a uniformly random one (including Flip).
+// FromTop moves are only played when exploring: one ply is not enough to see why taking a
+// card off the foundation would help, and it lets the greedy agent cycle on and off it.
+type TDStrategy struct {
+	Net *ValueNet
+	Epsilon float64
+}
+
+func (strat TDStrategy) choose(g *game.Game, moves *Moves, rng *rand.Rand) int {
+	if strat.Epsilon > 0 && rng.Float64() < strat.Epsilon {
+		return rng.Intn(moves.len() + 1) - 1
+	}
+
+	// Ties go to the first move, so an untrained net does not just flip forever
+	values := AfterstateValues(g, moves, strat.Net)
+	best := -1
+	for i,v := range values {
+		if i < moves.len() && moves.At(i).Kind == FromTop {
+			continue
+		}
+		if best == -1 || v > values[best] {
+			best = i
+		}
+	}
+	if best == moves.len() {
+		return -1
+	}
+	return best
+}
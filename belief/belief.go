@@ -0,0 +1,209 @@
+package belief
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+
+	"solitaire/deck"
+	"solitaire/game"
+)
+
+// What the player can know about the cards it hasn't seen. A card is seen once it has been
+// face up: on the foundation, in a visible queue, in the Avail, or anywhere in the Deck once
+// it has been through once. Every other card is in one of the unknown slots, the face-down
+// tableau cards and (until the first pass is done) the Deck, and nothing the player has
+// seen says which: any arrangement of the unseen cards over the unknown slots is equally
+// likely. So the probability of a card being in a slot is just 1 / number of unknown slots.
+//
+// Nothing ever leaves view again once seen (the foundation is just counts per suit), so
+// the current view says everything the history of views does. Observe still checks that
+// views follow on from each other, which catches feeding it views from different games.
+type Belief struct {
+	view game.View
+	unseen []deck.Card
+	isUnseen [deck.NSuits][deck.SuitSize]bool
+}
+
+// Pile number of the Deck in a Slot. Piles 0 to game.NStacks-1 are the hidden stacks.
+const Stock = game.NStacks
+
+// An unknown position. Index counts from the bottom of a hidden stack, so the card that
+// will be turned up next is the last one, and from the front of the Deck, so index 0 is
+// the next card flipped.
+type Slot struct {
+	Pile int
+	Index int
+}
+
+func New(view game.View) *Belief {
+	b := &Belief{view: view}
+	var seen [deck.NSuits][deck.SuitSize]bool
+	for suit,size := range view.SuitStacks {
+		for rank := range size {
+			seen[suit][rank] = true
+		}
+	}
+	markSeen := func(cards []deck.Card) {
+		for _,c := range cards {
+			seen[c.Suit][c.Rank] = true
+		}
+	}
+	for _,queue := range view.VisibleQueues {
+		markSeen(queue)
+	}
+	markSeen(view.Avail)
+	markSeen(view.Deck)
+
+	for suit := range deck.NSuits {
+		for rank := range deck.SuitSize {
+			if !seen[suit][rank] {
+				b.unseen = append(b.unseen, deck.NewCard(rank, suit))
+				b.isUnseen[suit][rank] = true
+			}
+		}
+	}
+	return b
+}
+
+// Move on to the next view of the same game
+func (b *Belief) Observe(view game.View) error {
+	next := New(view)
+	for _,c := range next.unseen {
+		if !b.isUnseen[c.Suit][c.Rank] {
+			return fmt.Errorf("card %v was seen before but is unseen now", c)
+		}
+	}
+	if len(next.unseen) != next.NSlots() {
+		return fmt.Errorf("%v unseen cards for %v unknown slots", len(next.unseen), next.NSlots())
+	}
+	*b = *next
+	return nil
+}
+
+func (b *Belief) View() game.View {
+	return b.view
+}
+
+// Unseen cards, by suit and then rank
+func (b *Belief) Unseen() []deck.Card {
+	return slices.Clone(b.unseen)
+}
+
+func (b *Belief) IsUnseen(card deck.Card) bool {
+	return b.isUnseen[card.Suit][card.Rank]
+}
+
+// Whether the order of the Deck is known
+func (b *Belief) StockKnown() bool {
+	return b.view.Deck != nil || b.view.NDeck == 0
+}
+
+// The Deck from the next card to be flipped, once a full pass has shown it
+func (b *Belief) StockOrder() ([]deck.Card,bool) {
+	if !b.StockKnown() {
+		return nil,false
+	}
+	return slices.Clone(b.view.Deck),true
+}
+
+// Number of unknown slots in `pile`
+func (b *Belief) PileSize(pile int) int {
+	if pile == Stock {
+		if b.StockKnown() {
+			return 0
+		}
+		return b.view.NDeck
+	}
+	return b.view.NHidden[pile]
+}
+
+func (b *Belief) NSlots() int {
+	return len(b.unseen)
+}
+
+// All unknown slots, i.e. every place an unseen card could be
+func (b *Belief) Slots() []Slot {
+	slots := make([]Slot, 0, len(b.unseen))
+	for pile := range Stock + 1 {
+		for i := range b.PileSize(pile) {
+			slots = append(slots, Slot{pile, i})
+		}
+	}
+	return slots
+}
+
+// Where `card` could be: every unknown slot if it is unseen, none if it has been seen
+func (b *Belief) Candidates(card deck.Card) []Slot {
+	if !b.IsUnseen(card) {
+		return nil
+	}
+	return b.Slots()
+}
+
+// Probability that `card` is at `slot`
+func (b *Belief) ProbAt(card deck.Card, slot Slot) float64 {
+	if !b.IsUnseen(card) || slot.Index < 0 || slot.Index >= b.PileSize(slot.Pile) {
+		return 0
+	}
+	return 1 / float64(len(b.unseen))
+}
+
+// Probability that `card` is somewhere in `pile`, e.g. ProbInPile(5♥, 6) for it being
+// under column 6
+func (b *Belief) ProbInPile(card deck.Card, pile int) float64 {
+	if !b.IsUnseen(card) {
+		return 0
+	}
+	return float64(b.PileSize(pile)) / float64(len(b.unseen))
+}
+
+// Expected number of unknown cards turned up until one matching `match` is, counting that
+// one. The unseen cards are in random order, so whichever order they come up in this is
+// the expected position of the first match in a random permutation: (n+1)/(k+1) for k
+// matches out of n. +Inf if no unseen card matches.
+func (b *Belief) ExpectedRevealsUntil(match func(deck.Card) bool) float64 {
+	k := 0
+	for _,c := range b.unseen {
+		if match(c) {
+			k++
+		}
+	}
+	if k == 0 {
+		return math.Inf(1)
+	}
+	return float64(len(b.unseen) + 1) / float64(k + 1)
+}
+
+func (b *Belief) ExpectedRevealsToAce() float64 {
+	return b.ExpectedRevealsUntil(func(c deck.Card) bool { return c.Rank == deck.Ace })
+}
+
+// A full game consistent with everything seen, with the unseen cards dealt at random into
+// the unknown slots. For search agents to play out possible worlds in.
+func (b *Belief) Sample(rng *rand.Rand, rules game.Rules) *game.Game {
+	cards := slices.Clone(b.unseen)
+	rng.Shuffle(len(cards), func(i, j int) {
+		cards[i], cards[j] = cards[j], cards[i]
+	})
+
+	g := &game.Game{
+		Rules: rules,
+		SuitStacks: b.view.SuitStacks,
+		Avail: append(make([]deck.Card, 0, len(b.view.Avail) + b.view.NDeck), b.view.Avail...),
+		Passes: b.view.Passes,
+	}
+	for i := range game.NStacks {
+		n := b.view.NHidden[i]
+		g.HiddenStacks[i] = append([]deck.Card(nil), cards[:n]...)
+		cards = cards[n:]
+		g.VisibleQueues[i] = append(make([]deck.Card, 0, deck.SuitSize), b.view.VisibleQueues[i]...)
+	}
+	if b.StockKnown() {
+		g.Deck = slices.Clone(b.view.Deck)
+	} else {
+		g.Deck = cards
+	}
+	return g
+}
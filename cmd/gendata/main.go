@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+
+	"solitaire/dataset"
+	"solitaire/game"
+	"solitaire/registry"
+)
+
+// Generate a self-play dataset with any strategy from the registry
+func main() {
+	name := flag.String("strategy", "probabilistic", "strategy to play with, see solitaire strategies")
+	var params registry.ParamFlag
+	flag.Var(&params, "param", "strategy parameter as NAME=VALUE, can be repeated")
+	rulesName := flag.String("rules", "draw3", "rules as draw1 or draw3, optionally with a limit on passes through the deck, e.g. draw3:passes=3")
+	out := flag.String("out", "dataset_output", "output directory")
+	firstSeed := flag.Int64("seed", 0, "seed of the first game")
+	nGames := flag.Int("games", 1000, "number of games")
+	perShard := flag.Int("shard-size", 1000, "games per shard")
+	format := flag.String("format", "jsonl", "jsonl or bin")
+	workers := flag.Int("workers", runtime.NumCPU(), "shards written in parallel")
+	flag.Parse()
+
+	rules,err := game.ParseRules(*rulesName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	values,err := registry.ParseParams(params)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	all,err := registry.Resolve(*name, values)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	strategy,err := registry.New(*name, all)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	manifest,err := dataset.Generate(dataset.Config{
+		Strategy: strategy,
+		StrategyName: *name,
+		Params: all,
+		Rules: rules,
+		FirstSeed: *firstSeed,
+		NGames: *nGames,
+		GamesPerShard: *perShard,
+		Workers: *workers,
+		Format: dataset.Format(*format),
+		OutDir: *out,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	records, wins := 0, 0
+	for _,s := range manifest.Shards {
+		records += s.NRecords
+		wins += s.NWins
+	}
+	fmt.Printf("Wrote %v records from %v games (%v won) in %v shards to %v\n",
+		records, manifest.NGames, wins, len(manifest.Shards), *out)
+}
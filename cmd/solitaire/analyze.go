@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"solitaire/deal"
+	"solitaire/deck"
+	"solitaire/game"
+	"solitaire/solver"
+)
+
+// Where a card is in a fresh deal
+type placement struct {
+	Card string `json:"card"`
+	Column int `json:"column"` // -1 if in the stock
+	Covered int `json:"covered"` // Tableau: cards on top of it
+	StockIndex int `json:"stock_index"` // Stock: cards flipped before it, -1 if in the tableau
+	FirstPass bool `json:"first_pass"` // Stock: comes up on top of the Avail on the first pass
+}
+
+func place(g *game.Game, c deck.Card) placement {
+	for i := range game.NStacks {
+		hidden := g.HiddenStacks[i]
+		for j,h := range hidden {
+			if h == c {
+				return placement{Card: c.String(), Column: i, Covered: len(hidden)-1-j + len(g.VisibleQueues[i]), StockIndex: -1}
+			}
+		}
+		for j,v := range g.VisibleQueues[i] {
+			if v == c {
+				return placement{Card: c.String(), Column: i, Covered: j, StockIndex: -1}
+			}
+		}
+	}
+	nFlip := g.Rules.NFlip
+	for j,d := range g.Deck {
+		if d == c {
+			firstPass := j % nFlip == nFlip - 1 || j == len(g.Deck) - 1
+			return placement{Card: c.String(), Column: -1, StockIndex: j, FirstPass: firstPass}
+		}
+	}
+	panic(fmt.Sprintf("card %v is nowhere", c))
+}
+
+func (p placement) String() string {
+	if p.Column >= 0 {
+		return fmt.Sprintf("%v: column %v under %v", p.Card, p.Column, p.Covered)
+	}
+	s := fmt.Sprintf("%v: stock, card %v", p.Card, p.StockIndex+1)
+	if p.FirstPass {
+		s += ", playable on the first pass"
+	}
+	return s
+}
+
+func addModelFlag(fs *flag.FlagSet) *string {
+	return fs.String("model", "", "difficulty model from calibrate --save (default: fitted on StockStrategy, draw 3)")
+}
+
+func loadModel(path string) (deal.Model,error) {
+	if path == "" {
+		return deal.Default,nil
+	}
+	return deal.LoadModel(path)
+}
+
+func runAnalyze(args []string) error {
+	fs := newFlagSet("analyze")
+	rules := addRulesFlag(fs)
+	seed := addSeedFlag(fs, "the deal")
+	code := addDealFlag(fs)
+	out := addFormatFlag(fs)
+	maxNodes := fs.Int("max-nodes", 1000000, "positions the solver searches, 0 to skip solving")
+	modelPath := addModelFlag(fs)
+	if err := parseFlags(fs, args, false); err != nil {
+		return err
+	}
+	model,err := loadModel(*modelPath)
+	if err != nil {
+		return err
+	}
+
+	d,err := flagDeck(*seed, *code)
+	if err != nil {
+		return err
+	}
+	g := game.NewGameWithRules(d, *rules)
+	var aces, twos, kings []placement
+	for suit := range deck.NSuits {
+		aces = append(aces, place(g, deck.NewCard(int(deck.Ace), suit)))
+		twos = append(twos, place(g, deck.NewCard(int(deck.Two), suit)))
+		kings = append(kings, place(g, deck.NewCard(int(deck.King), suit)))
+	}
+	features := deal.Analyze(d, *rules)
+
+	summary := struct {
+		Seed int64 `json:"seed"`
+		Deal string `json:"deal"`
+		Rules string `json:"rules"`
+		Aces []placement `json:"aces"`
+		Twos []placement `json:"twos"`
+		Kings []placement `json:"kings"`
+		Features deal.Features `json:"features"`
+		WinChance float64 `json:"win_chance"` // Of the strategy the model was fitted on
+		Difficulty float64 `json:"difficulty"` // 0 easiest to 1 hardest, see deal.Model
+		Status string `json:"status,omitempty"`
+		Nodes int `json:"nodes,omitempty"`
+		SolutionLength int `json:"solution_length,omitempty"`
+	}{Seed: *seed, Deal: deal.Code(d), Rules: rules.String(), Aces: aces, Twos: twos, Kings: kings, Features: features,
+		WinChance: model.WinChance(features)}
+
+	status := solver.Unknown
+	var elapsed time.Duration
+	if *maxNodes > 0 {
+		start := time.Now()
+		r := solver.Solve(g, *maxNodes)
+		elapsed = time.Since(start)
+		status = r.Status
+		summary.Status = r.Status.String()
+		summary.Nodes = r.Nodes
+		summary.SolutionLength = len(r.Moves)
+	}
+	summary.Difficulty = model.Rate(features, status)
+
+	err = out.print(summary, func() {
+		fmt.Printf("Deal %v, %v\n", *seed, rules)
+		g.Display(false)
+		for _,group := range []struct{ name string; cards []placement }{{"Aces", aces}, {"Twos", twos}, {"Kings", kings}} {
+			fmt.Printf("\n%v:\n", group.name)
+			for _,p := range group.cards {
+				fmt.Println("  ", p)
+			}
+		}
+		f := features
+		fmt.Printf("\n%v cards on top of aces, %v on twos, %v aces in the stock\n", f.AcesCovered, f.TwosCovered, f.AcesInStock)
+		fmt.Printf("%v same-colour blocking pairs, %v kings not at the bottom of their column\n", f.BlockingPairs, f.KingsBuried)
+		fmt.Printf("%v stock cards playable on the first pass (%v aces and twos), %v opening moves\n", f.StockPlayable, f.StockLow, f.OpeningMoves)
+		if *maxNodes > 0 {
+			fmt.Printf("Solver: %v after %v positions in %v", summary.Status, summary.Nodes, elapsed.Round(time.Millisecond))
+			if status == solver.Won {
+				fmt.Printf(", in %v moves", summary.SolutionLength)
+			}
+			fmt.Println()
+		}
+		fmt.Printf("Difficulty %.2f (%v wins it %.1f%% of the time, by the model)\n", summary.Difficulty, model.Strategy, 100*summary.WinChance)
+		if model.Rules != rules.String() {
+			fmt.Printf("The model was fitted on %v, not %v\n", model.Rules, rules)
+		}
+	})
+	if err != nil || *maxNodes == 0 {
+		return err
+	}
+	return solveStatus(status)
+}
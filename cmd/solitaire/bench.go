@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"solitaire/agent"
+	"solitaire/corpus"
+	"solitaire/deck"
+	"solitaire/experiment"
+	"solitaire/game"
+	"solitaire/sim"
+	"solitaire/solver"
+)
+
+func runBench(args []string) error {
+	fs := newFlagSet("bench")
+	strategy := addStrategyFlags(fs, "probabilistic")
+	rules := addRulesFlag(fs)
+	seed := addSeedFlag(fs, "seed of the first deal, the rest follow on")
+	out := addFormatFlag(fs)
+	games := fs.Int("games", 200, "number of games; with a corpus, the first this many of its deals (default all)")
+	workers := fs.Int("workers", 1, "games played in parallel; 1 gives the time per move")
+	version := fs.String("corpus", "", "play the deals of this shipped corpus (e.g. "+corpus.Latest+", see solitaire corpus) instead of seeds, and compare with what the solver can win")
+	corpusPath := fs.String("corpus-file", "", "like --corpus, but a corpus file from corpus --build")
+	if err := parseFlags(fs, args, false); err != nil {
+		return err
+	}
+
+	strat,err := strategy.build()
+	if err != nil {
+		return err
+	}
+	if *version != "" || *corpusPath != "" {
+		c,err := corpusFor(*version, *corpusPath, *rules)
+		if err != nil {
+			return err
+		}
+		if flagGiven(fs, "games") && *games < len(c.Entries) {
+			c.Entries = c.Entries[:*games]
+		}
+		return benchCorpus(strat, strategy.spec(), c, *rules, *workers, *out)
+	}
+
+	start := time.Now()
+	results := sim.PlayAll(strat, sim.SeedRange(*seed, *games), *workers, sim.Options{Rules: *rules})
+	elapsed := time.Since(start)
+	moves, wins := 0, 0
+	for _,r := range results {
+		moves += r.Moves
+		if r.Won {
+			wins++
+		}
+	}
+
+	secs := elapsed.Seconds()
+	summary := struct {
+		Strategy string `json:"strategy"`
+		Rules string `json:"rules"`
+		Games int `json:"games"`
+		Workers int `json:"workers"`
+		Seconds float64 `json:"seconds"`
+		GamesPerSec float64 `json:"games_per_sec"`
+		MovesPerSec float64 `json:"moves_per_sec"`
+		WinRate float64 `json:"win_rate"`
+	}{strategy.spec(), rules.String(), *games, *workers, secs,
+		float64(*games) / secs, float64(moves) / secs, float64(wins) / float64(max(*games, 1))}
+	return out.print(summary, func() {
+		fmt.Printf("%v: %v games, %v moves in %v with %v workers\n",
+			summary.Strategy, *games, moves, elapsed.Round(time.Millisecond), *workers)
+		fmt.Printf("%.1f games/s, %.0f moves/s, %.1fµs/move, won %.1f%%\n",
+			summary.GamesPerSec, summary.MovesPerSec, 1e6*secs/float64(max(moves, 1)), 100*summary.WinRate)
+	})
+}
+
+// Wins out of deals, with a 95% interval
+type fraction struct {
+	Wins int `json:"wins"`
+	Of int `json:"of"`
+	Rate float64 `json:"rate"`
+	Lo float64 `json:"lo"`
+	Hi float64 `json:"hi"`
+}
+
+func newFraction(wins, of int) fraction {
+	lo,hi := experiment.Wilson(wins, of, 1.96)
+	return fraction{wins, of, float64(wins) / float64(max(of, 1)), lo, hi}
+}
+
+func (f fraction) String() string {
+	return fmt.Sprintf("%v/%v = %.1f%% (%.1f-%.1f%%)", f.Wins, f.Of, 100*f.Rate, 100*f.Lo, 100*f.Hi)
+}
+
+// Play every deal of `c`, and put the wins against the deals the solver won
+func benchCorpus(strat agent.Strategy, spec string, c corpus.Corpus, rules game.Rules, workers int, out format) error {
+	decks := make([]deck.Deck, len(c.Entries))
+	for i,e := range c.Entries {
+		d,err := e.Deck()
+		if err != nil {
+			return fmt.Errorf("corpus %v %v, deal %v: %w", c.Version, c.Rules, i, err)
+		}
+		decks[i] = d
+	}
+
+	start := time.Now()
+	wins := make(map[solver.Status]int)
+	total := 0
+	n := len(decks)
+	sim.Ordered(n, workers, nil, func(i int) bool {
+		// Seeding the agent from the deal's seed plays the same game as sim.Play of that seed
+		return sim.PlayDeck(strat, decks[i], c.Entries[i].Seed, sim.Options{Rules: rules}).Won
+	}, func(i int, won bool) {
+		if won {
+			wins[c.Entries[i].Status]++
+			total++
+		}
+	})
+	elapsed := time.Since(start)
+
+	var solvable, unwinnable, unknown int
+	for _,e := range c.Entries[:n] {
+		switch e.Status {
+		case solver.Won:
+			solvable++
+		case solver.Unwinnable:
+			unwinnable++
+		default:
+			unknown++
+		}
+	}
+	summary := struct {
+		Strategy string `json:"strategy"`
+		Corpus string `json:"corpus"`
+		Rules string `json:"rules"`
+		Seconds float64 `json:"seconds"`
+		WinRate fraction `json:"win_rate"` // Over all the deals
+		Ceiling fraction `json:"ceiling"` // Deals the solver won
+		OfSolvable fraction `json:"of_solvable"` // The strategy's wins among the deals the solver won
+		Unknown int `json:"unknown"` // Deals the solver gave up on
+		WonUnknown int `json:"won_unknown"` // Of those, won by the strategy, so winnable after all
+		WonUnwinnable int `json:"won_unwinnable"` // Should be 0, see solver.Status
+	}{spec, c.Version, c.Rules, elapsed.Seconds(), newFraction(total, n), newFraction(solvable, n),
+		newFraction(wins[solver.Won], solvable), unknown, wins[solver.Unknown], wins[solver.Unwinnable]}
+	return out.print(summary, func() {
+		fmt.Printf("%v on corpus %v, %v: %v deals in %v\n", spec, c.Version, c.Rules, n, elapsed.Round(time.Millisecond))
+		fmt.Printf("Won          %v\n", summary.WinRate)
+		fmt.Printf("Solver won   %v, the ceiling", summary.Ceiling)
+		if unknown > 0 {
+			fmt.Printf(" (up to %.1f%% counting the %v it gave up on)", 100 * float64(solvable + unknown) / float64(n), unknown)
+		}
+		fmt.Println()
+		fmt.Printf("Of those     %v\n", summary.OfSolvable)
+		if summary.WonUnknown > 0 {
+			fmt.Printf("Also won %v of the deals the solver gave up on\n", summary.WonUnknown)
+		}
+		if summary.WonUnwinnable > 0 {
+			fmt.Printf("Won %v deals the solver calls unwinnable! Its search is incomplete, see the solver package\n", summary.WonUnwinnable)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+
+	"solitaire/deal"
+	"solitaire/sim"
+)
+
+func runCalibrate(args []string) error {
+	fs := newFlagSet("calibrate")
+	strategy := addStrategyFlags(fs, "stock")
+	rules := addRulesFlag(fs)
+	seed := fs.Int64("seed", 2_000_000, "seed of the first deal, the rest follow on")
+	out := addFormatFlag(fs)
+	games := fs.Int("games", 20000, "deals to play; the model is fitted on 80% and checked on the rest")
+	workers := fs.Int("workers", runtime.NumCPU(), "games played in parallel")
+	save := fs.String("save", "", "write the model here, for analyze --model")
+	if err := parseFlags(fs, args, false); err != nil {
+		return err
+	}
+	if *games < 10 {
+		return usageError{fmt.Errorf("need at least 10 games, got %v", *games)}
+	}
+
+	strat,err := strategy.build()
+	if err != nil {
+		return err
+	}
+	seeds := sim.SeedRange(*seed, *games)
+	results := sim.PlayAll(strat, seeds, *workers, sim.Options{Rules: *rules})
+	features := make([]deal.Features, len(seeds))
+	won := make([]bool, len(seeds))
+	for i,s := range seeds {
+		features[i] = deal.Analyze(sim.Deal(s), *rules)
+		won[i] = results[i].Won
+	}
+
+	nFit := *games * 4 / 5
+	model := deal.Fit(features[:nFit], won[:nFit])
+	model.Strategy = strategy.spec()
+	model.Rules = rules.String()
+
+	// Check on the held-out deals, by fifths of difficulty
+	type band struct {
+		Difficulty string `json:"difficulty"`
+		Games int `json:"games"`
+		Predicted float64 `json:"predicted"`
+		Actual float64 `json:"actual"`
+	}
+	bands := make([]band, 5)
+	wins := make([]int, 5)
+	logLoss, baseLoss := 0.0, 0.0
+	base := 0.0
+	for _,w := range won[:nFit] {
+		if w {
+			base++
+		}
+	}
+	base /= float64(nFit)
+	for i := nFit; i < *games; i++ {
+		p := model.WinChance(features[i])
+		b := min(int(model.Difficulty(features[i]) * 5), 4)
+		bands[b].Games++
+		bands[b].Predicted += p
+		if won[i] {
+			wins[b]++
+			logLoss -= math.Log(p)
+			baseLoss -= math.Log(base)
+		} else {
+			logLoss -= math.Log(1 - p)
+			baseLoss -= math.Log(1 - base)
+		}
+	}
+	nTest := float64(*games - nFit)
+	for b := range bands {
+		bands[b].Difficulty = fmt.Sprintf("%.1f-%.1f", float64(b) / 5, float64(b+1) / 5)
+		if bands[b].Games > 0 {
+			bands[b].Predicted /= float64(bands[b].Games)
+			bands[b].Actual = float64(wins[b]) / float64(bands[b].Games)
+		}
+	}
+
+	if *save != "" {
+		if err := model.Save(*save); err != nil {
+			return err
+		}
+	}
+	report := struct {
+		Model deal.Model `json:"model"`
+		LogLoss float64 `json:"log_loss"` // On the held-out deals
+		BaseLogLoss float64 `json:"base_log_loss"` // Predicting the overall win rate for every deal
+		Bands []band `json:"bands"`
+	}{model, logLoss / nTest, baseLoss / nTest, bands}
+	return out.print(report, func() {
+		fmt.Printf("Fitted on %v deals played by %v, %v\n", nFit, model.Strategy, model.Rules)
+		fmt.Println("Weights, per standard deviation of each feature:")
+		for i,name := range model.Features {
+			fmt.Printf("  %-16v %+.3f\n", name, model.Weights[i])
+		}
+		fmt.Printf("Held-out log loss %.4f, against %.4f predicting %.2f%% for every deal\n", report.LogLoss, report.BaseLogLoss, 100*base)
+		fmt.Println("Held-out deals by difficulty:")
+		fmt.Printf("  %-10v %6v %10v %8v\n", "difficulty", "games", "predicted", "actual")
+		for _,b := range bands {
+			fmt.Printf("  %-10v %6v %9.1f%% %7.1f%%\n", b.Difficulty, b.Games, 100*b.Predicted, 100*b.Actual)
+		}
+		if *save != "" {
+			fmt.Printf("Model saved to %v\n", *save)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"solitaire/deal"
+	"solitaire/game"
+	"solitaire/sim"
+)
+
+func runCode(args []string) error {
+	fs := newFlagSet("code")
+	seed := addSeedFlag(fs, "the (first) deal to give the code of")
+	count := fs.Int("count", 1, "deals, from --seed on")
+	batch := fs.String("batch", "", "write the deals to this file in the binary batch format, instead of printing codes")
+	read := fs.String("read", "", "print the codes of the deals in this batch file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: solitaire code [flags] [<code>...]\n\nWith codes, shows those deals. Otherwise gives the codes for seeds.")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args, true); err != nil {
+		return err
+	}
+
+	for _,code := range fs.Args() {
+		d,err := deal.Decode(code)
+		if err != nil {
+			return usageError{err}
+		}
+		fmt.Printf("Deal %v:\n", code)
+		game.NewGame(d).Display(false)
+		fmt.Println("Cards in order:", d)
+	}
+	if fs.NArg() > 0 {
+		return nil
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	if *read != "" {
+		f,err := os.Open(*read)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		br := deal.NewBatchReader(f)
+		for {
+			d,err := br.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%v: %w", *read, err)
+			}
+			fmt.Fprintln(w, deal.Code(d))
+		}
+	}
+
+	if *batch == "" {
+		for _,s := range sim.SeedRange(*seed, *count) {
+			fmt.Fprintln(w, deal.Code(sim.Deal(s)))
+		}
+		return nil
+	}
+	f,err := os.Create(*batch)
+	if err != nil {
+		return err
+	}
+	bw := deal.NewBatchWriter(f)
+	for _,s := range sim.SeedRange(*seed, *count) {
+		if err := bw.Write(sim.Deal(s)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
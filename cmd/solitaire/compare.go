@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"time"
+
+	"solitaire/experiment"
+	"solitaire/registry"
+	"solitaire/sim"
+)
+
+type compared struct {
+	Strategy string `json:"strategy"`
+	Wins int `json:"wins"`
+	WinRate float64 `json:"win_rate"`
+	StdErr float64 `json:"stderr"`
+	// Against the first strategy, on the same deals
+	OnlyThis int `json:"only_this"` // Deals this one won and the first lost
+	OnlyFirst int `json:"only_first"`
+	Diff float64 `json:"diff"` // Win rate minus the first's
+	DiffStdErr float64 `json:"diff_stderr"`
+	Summary experiment.Summary `json:"summary"` // Moves, progress and so on
+}
+
+func runCompare(args []string) error {
+	fs := newFlagSet("compare")
+	rules := addRulesFlag(fs)
+	seed := addSeedFlag(fs, "seed of the first deal, the rest follow on")
+	out := addFormatFlag(fs)
+	games := fs.Int("games", 1000, "number of games for each strategy")
+	workers := fs.Int("workers", runtime.NumCPU(), "games played in parallel")
+	store := addStoreFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: solitaire compare [flags] <strategy> <strategy>...")
+		fmt.Fprintln(fs.Output(), "Strategies are NAME or NAME:PARAM=VALUE,PARAM=VALUE, e.g. expectimax:Depth=3")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args, true); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return usageError{fmt.Errorf("expected at least two strategies to compare")}
+	}
+
+	seeds := sim.SeedRange(*seed, *games)
+	n := float64(max(*games, 1))
+	var first []sim.GameResult
+	var rows []compared
+	for i,spec := range fs.Args() {
+		name,values,err := registry.ParseSpec(spec)
+		if err != nil {
+			return usageError{err}
+		}
+		strat,err := registry.New(name, values)
+		if err != nil {
+			return strategyError(err)
+		}
+		params,_ := registry.Resolve(name, values)
+		manifest := experiment.NewManifest("compare", name, params, *rules, *seed, *games)
+		results := sim.PlayAll(strat, seeds, *workers, sim.Options{Rules: *rules})
+		manifest.End = time.Now().UTC()
+		var summary experiment.Summary
+		for j,r := range results {
+			summary.Add(experiment.NewRow(seeds[j], r))
+		}
+		summary.Seconds = manifest.End.Sub(manifest.Start).Seconds()
+		if err := storeRun(*store, manifest, summary); err != nil {
+			return err
+		}
+		if i == 0 {
+			first = results
+		}
+		row := compared{Strategy: spec, Summary: summary}
+		for j,r := range results {
+			if r.Won {
+				row.Wins++
+			}
+			switch {
+			case r.Won && !first[j].Won:
+				row.OnlyThis++
+			case !r.Won && first[j].Won:
+				row.OnlyFirst++
+			}
+		}
+		p := float64(row.Wins) / n
+		row.WinRate = p
+		row.StdErr = math.Sqrt(p * (1 - p) / n)
+		// Each deal's difference is +1, -1 or 0
+		d := float64(row.OnlyThis - row.OnlyFirst) / n
+		row.Diff = d
+		row.DiffStdErr = math.Sqrt((float64(row.OnlyThis + row.OnlyFirst) / n - d*d) / n)
+		rows = append(rows, row)
+	}
+
+	summary := struct {
+		Rules string `json:"rules"`
+		FirstSeed int64 `json:"first_seed"`
+		Games int `json:"games"`
+		Strategies []compared `json:"strategies"`
+	}{rules.String(), *seed, *games, rows}
+	return out.print(summary, func() {
+		fmt.Printf("%v games from seed %v, %v\n", *games, *seed, rules)
+		for i,row := range rows {
+			fmt.Printf("%-40v %6.2f%% ± %.2f", row.Strategy, 100*row.WinRate, 100*row.StdErr)
+			if i > 0 {
+				fmt.Printf("   %+.2f%% ± %.2f vs first (won %v it lost, lost %v it won)",
+					100*row.Diff, 100*row.DiffStdErr, row.OnlyThis, row.OnlyFirst)
+			}
+			fmt.Println()
+		}
+		fmt.Printf("\nMeans per game, and for lost games the cards on the foundation and revealed at the end:\n")
+		fmt.Printf("%-40v %6v %10v %10v %13v\n", "", "moves", "stalled at", "lost found", "lost revealed")
+		for _,row := range rows {
+			s := row.Summary
+			fmt.Printf("%-40v %6.1f %10.1f %10.1f %13.1f\n", row.Strategy, s.MeanMoves, s.MeanStalledAt, s.LostMeanFoundation, s.LostMeanRevealed)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"solitaire/corpus"
+	"solitaire/game"
+	"solitaire/sim"
+)
+
+// The corpus flags of bench and corpus: a shipped version, or a file
+func corpusFor(version, path string, rules game.Rules) (corpus.Corpus,error) {
+	if path != "" {
+		c,err := corpus.ReadFile(path)
+		if err == nil && c.Rules != rules.String() {
+			err = fmt.Errorf("%v is for %v, not %v", path, c.Rules, rules)
+		}
+		return c,err
+	}
+	return corpus.Load(version, rules)
+}
+
+type corpusStats struct {
+	Version string `json:"version"`
+	Rules string `json:"rules"`
+	Deals int `json:"deals"`
+	Won int `json:"won"`
+	Unwinnable int `json:"unwinnable"`
+	Unknown int `json:"unknown"`
+	MaxNodes int `json:"max_nodes"`
+	MeanLength float64 `json:"mean_length"` // Of the solver's winning lines
+}
+
+func statsOf(c corpus.Corpus) corpusStats {
+	s := corpusStats{Version: c.Version, Rules: c.Rules, Deals: c.Deals, MaxNodes: c.MaxNodes}
+	s.Won, s.Unwinnable, s.Unknown = c.Counts()
+	for _,e := range c.Entries {
+		s.MeanLength += float64(e.Length)
+	}
+	s.MeanLength /= float64(max(s.Won, 1))
+	return s
+}
+
+func runCorpus(args []string) error {
+	fs := newFlagSet("corpus")
+	rules := addRulesFlag(fs)
+	out := addFormatFlag(fs)
+	build := fs.String("build", "", "label new deals with the solver and write the corpus to this file")
+	version := fs.String("version", corpus.Latest, "version to write in the header of a new corpus")
+	seed := fs.Int64("seed", 3_000_000, "with --build, seed of the first deal, the rest follow on")
+	games := fs.Int("games", 2000, "with --build, number of deals")
+	maxNodes := fs.Int("max-nodes", 500000, "with --build, positions the solver searches per deal")
+	workers := fs.Int("workers", runtime.NumCPU(), "with --build, deals solved in parallel")
+	if err := parseFlags(fs, args, false); err != nil {
+		return err
+	}
+
+	if *build == "" {
+		shipped,err := corpus.Shipped()
+		if err != nil {
+			return err
+		}
+		var stats []corpusStats
+		for _,c := range shipped {
+			stats = append(stats, statsOf(c))
+		}
+		return out.print(stats, func() {
+			fmt.Printf("%-8v %-16v %6v %7v %11v %8v %10v %10v\n", "version", "rules", "deals", "won", "unwinnable", "unknown", "mean moves", "max nodes")
+			for _,s := range stats {
+				fmt.Printf("%-8v %-16v %6v %6.1f%% %10.1f%% %7.1f%% %10.1f %10v\n", s.Version, s.Rules, s.Deals,
+					100 * float64(s.Won) / float64(s.Deals), 100 * float64(s.Unwinnable) / float64(s.Deals),
+					100 * float64(s.Unknown) / float64(s.Deals), s.MeanLength, s.MaxNodes)
+			}
+		})
+	}
+
+	c := corpus.Corpus{Header: corpus.Header{Version: *version, Rules: rules.String(), FirstSeed: *seed, Deals: *games, MaxNodes: *maxNodes}}
+	seeds := sim.SeedRange(*seed, *games)
+	start, logged := time.Now(), time.Now()
+	sim.Ordered(len(seeds), *workers, nil, func(i int) corpus.Entry {
+		return corpus.Label(seeds[i], *rules, *maxNodes)
+	}, func(i int, e corpus.Entry) {
+		c.Entries = append(c.Entries, e)
+		if time.Since(logged) > logEvery {
+			logged = time.Now()
+			fmt.Fprintf(os.Stderr, "%v/%v deals labelled in %v\n", i+1, *games, time.Since(start).Round(time.Second))
+		}
+	})
+	f,err := os.Create(*build)
+	if err != nil {
+		return err
+	}
+	if err := c.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	s := statsOf(c)
+	return out.print(s, func() {
+		fmt.Printf("%v deals for %v written to %v: %v won, %v unwinnable, %v unknown\n", s.Deals, s.Rules, *build, s.Won, s.Unwinnable, s.Unknown)
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"solitaire/deal"
+	"solitaire/deck"
+	"solitaire/game"
+	"solitaire/sim"
+)
+
+// Flags shared between commands. Each command adds the ones it needs.
+
+func newFlagSet(name string) *flag.FlagSet {
+	// ContinueOnError, so that main decides the exit code for bad flags like for the rest
+	return flag.NewFlagSet(name, flag.ContinueOnError)
+}
+
+// Parse, and check there are no arguments left over unless the command takes some
+func parseFlags(fs *flag.FlagSet, args []string, positional bool) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return exitStatus(exitUsage) // flag has already said what's wrong
+	}
+	if !positional && fs.NArg() > 0 {
+		return usageError{fmt.Errorf("unexpected arguments %v", fs.Args())}
+	}
+	return nil
+}
+
+func addSeedFlag(fs *flag.FlagSet, doc string) *int64 {
+	return fs.Int64("seed", 0, doc)
+}
+
+// A deal code from deal.Code, instead of --seed. Codes don't depend on Go's random
+// number generator like seeds do.
+func addDealFlag(fs *flag.FlagSet) *string {
+	return fs.String("deal", "", "the deal as a code (see solitaire code), instead of --seed")
+}
+
+// The deck for --deal if given, else --seed
+func flagDeck(seed int64, code string) (deck.Deck,error) {
+	if code == "" {
+		return sim.Deal(seed),nil
+	}
+	d,err := deal.Decode(code)
+	if err != nil {
+		return d,usageError{err}
+	}
+	return d,nil
+}
+
+// How to name the deal in messages
+func dealName(seed int64, code string) string {
+	if code == "" {
+		return fmt.Sprint(seed)
+	}
+	return code
+}
+
+type rulesFlag struct {
+	rules game.Rules
+}
+
+func (r *rulesFlag) String() string {
+	return r.rules.String()
+}
+
+func (r *rulesFlag) Set(s string) error {
+	rules,err := game.ParseRules(s)
+	r.rules = rules
+	return err
+}
+
+func addRulesFlag(fs *flag.FlagSet) *game.Rules {
+	r := &rulesFlag{game.DefaultRules}
+	fs.Var(r, "rules", "rules as draw1 or draw3, optionally with a limit on passes through the deck, e.g. draw3:passes=3")
+	return &r.rules
+}
+
+// --format=text|json. JSON goes to stdout as one indented object, for scripts.
+type format string
+
+func addFormatFlag(fs *flag.FlagSet) *format {
+	f := format("text")
+	fs.Func("format", "output format, text or json (default text)", func(s string) error {
+		if s != "text" && s != "json" {
+			return fmt.Errorf("expected text or json")
+		}
+		f = format(s)
+		return nil
+	})
+	return &f
+}
+
+// Print `v` as JSON, or call `text` to print it for people
+func (f format) print(v any, text func()) error {
+	if f != "json" {
+		text()
+		return nil
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Exit codes, so that scripts can tell what happened without parsing the output
+const (
+	exitOK = 0 // Also: the game was won, the deal is winnable
+	exitError = 1
+	exitUsage = 2 // Bad command or flags
+	exitLost = 3 // The game was lost, or the deal is unwinnable
+	exitUnknown = 4 // The solver gave up before finding out
+	exitInterrupted = 130 // Stopped by Ctrl-C, as shells report SIGINT
+)
+
+// An outcome that isn't an error but still gets its own exit code, like a lost game.
+// The command has already said what happened, so main doesn't print anything.
+type exitStatus int
+
+func (s exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(s))
+}
+
+// Bad flags or arguments
+type usageError struct {
+	error
+}
+
+type command struct {
+	name string
+	doc string
+	run func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	// In init, since help refers back to commands
+	commands = []command{
+		{"play", "play a game yourself, or watch a strategy play one", runPlay},
+		{"sim", "play many games with a strategy and report the win rate", runSim},
+		{"compare", "play strategies on the same deals and compare their win rates", runCompare},
+		{"solve", "find out whether a deal can be won with every card known", runSolve},
+		{"replay", "step through a game recorded with play --record", runReplay},
+		{"analyze", "describe a deal: where the aces and kings are, and whether it can be won", runAnalyze},
+		{"code", "turn seeds into short deal codes that don't depend on the random number generator, and back", runCode},
+		{"winnable", "generate deals that can be won, each with a winning line to prove it", runWinnable},
+		{"calibrate", "fit the deal difficulty model to the games a strategy wins", runCalibrate},
+		{"postmortem", "find out why a strategy loses: which cards were still stuck, and where", runPostmortem},
+		{"corpus", "list the benchmark deals shipped with the code, or label new ones with the solver", runCorpus},
+		{"winnability", "estimate the fraction of deals that can be won: what the best agent wins, and what the solver wins seeing every card", runWinnability},
+		{"bench", "play a strategy over the benchmark corpus and compare with the solver's ceiling, or time it", runBench},
+		{"results", "query the results of past runs: leaderboards, history, best parameters", runResults},
+		{"strategies", "list the strategies and their parameters", runStrategies},
+		{"help", "show this help", func([]string) error { usage(); return nil }},
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: solitaire <command> [flags]\n\nCommands:")
+	for _,c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12v %v\n", c.name, c.doc)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun solitaire <command> -h for the flags of a command.")
+	fmt.Fprintf(os.Stderr, "\nExit codes: %v ok or won, %v error, %v usage, %v lost or unwinnable, %v solver gave up, %v interrupted\n",
+		exitOK, exitError, exitUsage, exitLost, exitUnknown, exitInterrupted)
+}
+
+func main() {
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") {
+		usage()
+		os.Exit(exitUsage)
+	}
+	for _,c := range commands {
+		if c.name == os.Args[1] {
+			os.Exit(exitCode(c.run(os.Args[2:])))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+	usage()
+	os.Exit(exitUsage)
+}
+
+func exitCode(err error) int {
+	var status exitStatus
+	var usageErr usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &status):
+		return int(status)
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	fmt.Fprintln(os.Stderr, err)
+	return exitError
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+
+	"solitaire/agent"
+	"solitaire/deal"
+	"solitaire/deck"
+	"solitaire/game"
+	"solitaire/sim"
+	"solitaire/solver"
+)
+
+func runPlay(args []string) error {
+	fs := newFlagSet("play")
+	strategy := addStrategyFlags(fs, "")
+	rules := addRulesFlag(fs)
+	seed := addSeedFlag(fs, "the deal (default random)")
+	code := addDealFlag(fs)
+	out := addFormatFlag(fs)
+	record := fs.String("record", "", "write the game to this file, for replay")
+	quiet := fs.Bool("quiet", false, "when watching a strategy, only show the end of the game")
+	winnable := fs.Bool("winnable", false, "play a deal that can be won, with a winning line to show if you lose")
+	w := addWinnableFlags(fs)
+	deals := fs.String("deals", "", "with --winnable, pick from the deals saved by winnable --save instead of generating one")
+	if err := parseFlags(fs, args, false); err != nil {
+		return err
+	}
+	if *winnable && (flagGiven(fs, "seed") || *code != "") {
+		return usageError{fmt.Errorf("--winnable picks the deal, so can't be given --seed or --deal")}
+	}
+	if !flagGiven(fs, "seed") {
+		*seed = rand.Int63()
+	}
+
+	rec := gameRecord{Seed: *seed, Deal: *code, Rules: rules.String()}
+	if *winnable {
+		c,err := w.pick(*deals, *rules)
+		if err != nil {
+			return err
+		}
+		rec.Seed, rec.Deal = c.Seed, c.Deal
+		rec.Certificate = c.Moves
+		if *out != "json" {
+			fmt.Printf("Deal %v can be won (difficulty %.2f)\n", c.Deal, c.Difficulty)
+		}
+	}
+	d,err := rec.deck()
+	if err != nil {
+		return err
+	}
+	if *strategy.name == "" {
+		if err := playYourself(&rec, d, *rules); err != nil {
+			return err
+		}
+	} else {
+		strat,err := strategy.build()
+		if err != nil {
+			return err
+		}
+		rec.Strategy = strategy.spec()
+		opts := sim.Options{Rules: *rules, Verbose: !*quiet && *out != "json"}
+		opts.Observe = func(g *game.Game, moves *agent.Moves, moveID int) {
+			rec.Moves = append(rec.Moves, recordedMove(g, moves, moveID))
+		}
+		rec.Won = sim.PlayDeck(strat, d, rec.Seed, opts).Won
+	}
+
+	if *record != "" {
+		if err := writeRecord(*record, rec); err != nil {
+			return err
+		}
+	}
+	err = out.print(rec, func() {
+		if rec.Won {
+			fmt.Printf("Won deal %v in %v moves!\n", rec.name(), len(rec.Moves))
+		} else {
+			fmt.Printf("Lost deal %v after %v moves\n", rec.name(), len(rec.Moves))
+		}
+		if !rec.Won && rec.Certificate != nil {
+			fmt.Printf("It can be won in %v moves:\n", len(rec.Certificate))
+			for i,m := range rec.Certificate {
+				fmt.Printf("  %3v. %v\n", i+1, m)
+			}
+		}
+	})
+	if err == nil && !rec.Won {
+		return exitStatus(exitLost)
+	}
+	return err
+}
+
+func flagGiven(fs *flag.FlagSet, name string) bool {
+	given := false
+	fs.Visit(func(f *flag.Flag) {
+		given = given || f.Name == name
+	})
+	return given
+}
+
+const playHelp = `<Enter> or f to flip cards from the deck. To move cards,
+	a t to move from available (waste pile) to top (foundation)
+	a <dst> to move from available (waste pile) to stack <dst> (in tableau)
+	<src> <dst> <n> to move <n> cards from stack <src> to stack <dst> (in tableau)
+		Omit <n> to move all visible cards
+	<src> t to move from stack <src> (in tableau) to top (foundation)
+	t <src> <dst> to move from top (foundation) stack <src> to stack <dst> (in tableau)
+q to give up.`
+
+// Play by typing moves, until the game is won or you give up
+func playYourself(rec *gameRecord, d deck.Deck, rules game.Rules) error {
+	g := game.NewGameWithRules(d, rules)
+	fmt.Printf("Deal %v, %v. Type help for the moves.\n", rec.name(), rules)
+	if rec.Deal == "" {
+		fmt.Printf("To share it: --deal=%v\n", deal.Code(d))
+	}
+	g.Display(true)
+	// One reader for the whole game, so piped input isn't lost in a buffer
+	in := bufio.NewScanner(os.Stdin)
+	for !g.IsWon() {
+		fmt.Print("Enter a move: ")
+		if !in.Scan() {
+			fmt.Println()
+			return in.Err() // Out of input counts as giving up
+		}
+		line := strings.ToLower(strings.TrimSpace(in.Text()))
+		switch line {
+		case "h", "help":
+			fmt.Println(playHelp)
+			continue
+		case "q", "quit":
+			fmt.Println()
+			return nil
+		}
+		m,err := parseMove(g, line)
+		if err == nil {
+			err = solver.Apply(g, m)
+		}
+		if err != nil {
+			fmt.Printf("Move error: %v\n", err)
+			continue
+		}
+		rec.Moves = append(rec.Moves, m)
+		g.Display(true)
+	}
+	rec.Won = true
+	return nil
+}
+
+// A move as typed, see playHelp
+func parseMove(g *game.Game, line string) (solver.Move,error) {
+	fields := strings.Fields(line)
+	ints := func(ss []string) ([]int,error) {
+		var ns []int
+		for _,s := range ss {
+			n,err := strconv.Atoi(s)
+			if err != nil {
+				return nil,fmt.Errorf("expected a number, got %q", s)
+			}
+			ns = append(ns, n)
+		}
+		return ns,nil
+	}
+	none := -1
+	switch {
+	case len(fields) == 0 || line == "f":
+		return solver.Move{Kind: agent.Flip, Src: none, Dst: none},nil
+	case line == "a t":
+		return solver.Move{Kind: agent.AvailToTop, Src: none, Dst: none},nil
+	case fields[0] == "a" && len(fields) == 2:
+		ns,err := ints(fields[1:])
+		if err != nil {
+			return solver.Move{},err
+		}
+		return solver.Move{Kind: agent.AvailToTableau, Src: none, Dst: ns[0]},nil
+	case fields[0] == "t" && len(fields) == 3:
+		ns,err := ints(fields[1:])
+		if err != nil {
+			return solver.Move{},err
+		}
+		return solver.Move{Kind: agent.FromTop, Src: ns[0], Dst: ns[1]},nil
+	case len(fields) == 2 && fields[1] == "t":
+		ns,err := ints(fields[:1])
+		if err != nil {
+			return solver.Move{},err
+		}
+		return solver.Move{Kind: agent.ToTop, Src: ns[0], Dst: none},nil
+	case len(fields) == 2 || len(fields) == 3:
+		ns,err := ints(fields)
+		if err != nil {
+			return solver.Move{},err
+		}
+		if ns[0] < 0 || ns[0] >= game.NStacks {
+			return solver.Move{},fmt.Errorf("no stack %v", ns[0])
+		}
+		n := len(g.VisibleQueues[ns[0]])
+		if len(ns) == 3 {
+			n = ns[2]
+		}
+		return solver.Move{Kind: agent.Tableau, Src: ns[0], Dst: ns[1], N: n},nil
+	}
+	return solver.Move{},fmt.Errorf("invalid move command, see help")
+}
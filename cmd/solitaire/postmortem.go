@@ -0,0 +1,130 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+
+	"solitaire/deck"
+	"solitaire/game"
+	"solitaire/postmortem"
+	"solitaire/sim"
+)
+
+func runPostmortem(args []string) error {
+	fs := newFlagSet("postmortem")
+	strategy := addStrategyFlags(fs, "probabilistic")
+	rules := addRulesFlag(fs)
+	seed := addSeedFlag(fs, "seed of the first deal, the rest follow on")
+	out := addFormatFlag(fs)
+	games := fs.Int("games", 1000, "number of games to play, of which the lost ones are analysed")
+	workers := fs.Int("workers", runtime.NumCPU(), "games played in parallel")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: solitaire postmortem [flags] [record.json...]")
+		fmt.Fprintln(fs.Output(), "Analyses the lost games among the records given, or else plays --games with --strategy.")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args, true); err != nil {
+		return err
+	}
+
+	report := postmortem.New()
+	source := ""
+	if fs.NArg() > 0 {
+		for _,path := range fs.Args() {
+			rec,err := readRecord(path)
+			if err != nil {
+				return err
+			}
+			g,err := finalState(rec)
+			if err != nil {
+				return fmt.Errorf("%v: %w", path, err)
+			}
+			if !g.IsWon() {
+				report.Add(g)
+			}
+		}
+		source = fmt.Sprintf("%v records", fs.NArg())
+	} else {
+		strat,err := strategy.build()
+		if err != nil {
+			return err
+		}
+		for _,r := range sim.PlayAll(strat, sim.SeedRange(*seed, *games), *workers, sim.Options{Rules: *rules}) {
+			if !r.Won {
+				report.Add(r.Game)
+			}
+		}
+		source = fmt.Sprintf("%v games of %v, %v, from seed %v", *games, strategy.spec(), rules, *seed)
+	}
+	return out.print(report, func() { printPostmortem(report, source) })
+}
+
+func printPostmortem(r *postmortem.Report, source string) {
+	fmt.Printf("Post-mortem of %v lost games (%v)\n", r.Games, source)
+	if r.Games == 0 {
+		return
+	}
+	pct := func(n int) float64 {
+		return 100 * float64(n) / float64(r.Games)
+	}
+	fmt.Printf("%.1f cards still face down at the end, on average\n", r.MeanHidden)
+	fmt.Printf("An ace still face down: %.1f%% of losses\n", pct(r.BuriedAce))
+	fmt.Printf("A king on face-down cards and no empty column for it: %.1f%% of losses\n", pct(r.KingNeedsColumn))
+
+	fmt.Println("\nStill face down at the end, % of lost games, by where dealt:")
+	printHeatmap(r.HiddenAt, pct)
+
+	fmt.Println("\nThe next card each foundation needed was:")
+	total := 0
+	for _,n := range r.Blockers {
+		total += n
+	}
+	for _,where := range []string{postmortem.Hidden, postmortem.Covered, postmortem.InStock, postmortem.Free} {
+		fmt.Printf("  %-8v %5.1f%%\n", where, 100 * float64(r.Blockers[where]) / float64(max(total, 1)))
+	}
+	fmt.Println("Face down, by rank:")
+	fmt.Print("  ")
+	for rank,n := range r.HiddenBlockersByRank {
+		fmt.Printf("%v %v  ", deck.RankT(rank), n)
+	}
+	fmt.Println("\nFace down, by suit:")
+	fmt.Print("  ")
+	for suit,n := range r.HiddenBlockersBySuit {
+		fmt.Printf("%v %v  ", deck.SuitT(suit), n)
+	}
+	fmt.Println("\nFace down, % of lost games, by where dealt:")
+	printHeatmap(r.HiddenBlockersAt, pct)
+
+	cards := slices.SortedFunc(maps.Keys(r.HiddenCards), func(a, b string) int {
+		return cmp.Or(cmp.Compare(r.HiddenCards[b], r.HiddenCards[a]), strings.Compare(a, b))
+	})
+	fmt.Println("\nMost often still face down:")
+	for _,c := range cards[:min(len(cards), 10)] {
+		fmt.Printf("  %-4v %5.1f%%\n", c, pct(r.HiddenCards[c]))
+	}
+}
+
+// Rows by depth from the top at the deal, columns by column. Column 0 and depth 0 are
+// left out: nothing there is dealt face down.
+func printHeatmap(counts [game.NStacks][game.NStacks]int, pct func(int) float64) {
+	fmt.Print("  depth")
+	for col := 1; col < game.NStacks; col++ {
+		fmt.Printf("  col %v", col)
+	}
+	fmt.Println()
+	for depth := 1; depth < game.NStacks; depth++ {
+		fmt.Printf("  %5v", depth)
+		for col := 1; col < game.NStacks; col++ {
+			if depth > col {
+				fmt.Printf("  %5v", "")
+			} else {
+				fmt.Printf("  %5.1f", pct(counts[col][depth]))
+			}
+		}
+		fmt.Println()
+	}
+}
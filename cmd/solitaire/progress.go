@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"solitaire/experiment"
+	"solitaire/sim"
+)
+
+// How often the progress display is redrawn on a terminal, and logged otherwise
+const (
+	redrawEvery = 200 * time.Millisecond
+	logEvery = 10 * time.Second
+)
+
+// Progress of a long run on stderr: redrawn in place on a terminal, a log line now and
+// then when stderr is a file or pipe. Rows can be added from any goroutine.
+type progress struct {
+	title string
+	total int // Games in the whole run
+	w io.Writer
+	tty bool
+
+	mu sync.Mutex
+	summary experiment.Summary // Including games done before a resume
+	session int // Games done since start
+	start time.Time
+	lostFoundation [53]int // Lost games by cards on the foundation, this session
+	drawn int // Lines drawn last time, to draw over
+
+	quit chan struct{}
+	finished chan struct{}
+}
+
+// mode is auto, tty, log or off
+func newProgress(mode, title string, total int, summary experiment.Summary) *progress {
+	p := &progress{title: title, total: total, w: os.Stderr, summary: summary, start: time.Now()}
+	switch mode {
+	case "off":
+		return nil
+	case "auto":
+		info,err := os.Stderr.Stat()
+		p.tty = err == nil && info.Mode() & os.ModeCharDevice != 0
+	case "tty":
+		p.tty = true
+	}
+	return p
+}
+
+func addProgressFlag(fs *flag.FlagSet) *string {
+	return fs.String("progress", "auto", "progress on stderr: tty (redrawn in place), log (a line every 10s), off, or auto (tty if stderr is a terminal)")
+}
+
+func checkProgressMode(mode string) error {
+	switch mode {
+	case "auto", "tty", "log", "off":
+		return nil
+	}
+	return usageError{fmt.Errorf("bad --progress %q, expected auto, tty, log or off", mode)}
+}
+
+// Start showing progress, until stop. Does nothing on a nil progress, as for --progress=off.
+func (p *progress) run() {
+	if p == nil {
+		return
+	}
+	p.quit = make(chan struct{})
+	p.finished = make(chan struct{})
+	every := logEvery
+	if p.tty {
+		every = redrawEvery
+	}
+	go func() {
+		defer close(p.finished)
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.show()
+			case <-p.quit:
+				p.show()
+				return
+			}
+		}
+	}()
+}
+
+func (p *progress) stop() {
+	if p == nil {
+		return
+	}
+	close(p.quit)
+	<-p.finished
+}
+
+func (p *progress) add(row experiment.Row) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.summary.Add(row)
+	p.session++
+	if !row.Won {
+		p.lostFoundation[row.Foundation]++
+	}
+}
+
+func (p *progress) show() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.summary
+	elapsed := time.Since(p.start)
+	rate := float64(p.session) / elapsed.Seconds()
+	eta := "?"
+	if rate > 0 {
+		eta = (time.Duration(float64(p.total - s.Games) / rate) * time.Second).Round(time.Second).String()
+	}
+	status := fmt.Sprintf("%v/%v games, %.1f games/s, ETA %v", s.Games, p.total, rate, eta)
+	winRate := fmt.Sprintf("won %.2f%% (95%% CI %.2f-%.2f%%)", 100*s.WinRate, 100*s.CILow, 100*s.CIHigh)
+
+	if !p.tty {
+		fmt.Fprintf(p.w, "%v %v: %v, %v\n", time.Now().Format("15:04:05"), p.title, status, winRate)
+		return
+	}
+
+	lines := []string{p.title + ": " + status, strings.ToUpper(winRate[:1]) + winRate[1:]}
+	var ends []string
+	for end := sim.Won; end <= sim.TooLong; end++ {
+		ends = append(ends, fmt.Sprintf("%v %v", end, s.Ends[end.String()]))
+	}
+	lines = append(lines, "Ends: " + strings.Join(ends, ", "), "Cards on the foundation in lost games:")
+	const bucket = 4
+	var counts []int
+	most := 1
+	for lo := 0; lo < 52; lo += bucket {
+		n := 0
+		for _,c := range p.lostFoundation[lo:lo+bucket] {
+			n += c
+		}
+		counts = append(counts, n)
+		most = max(most, n)
+	}
+	for i,n := range counts {
+		lines = append(lines, fmt.Sprintf("  %2v-%-2v %-40v %v", i*bucket, i*bucket + bucket - 1, strings.Repeat("#", n*40/most), n))
+	}
+
+	// Back up over the last drawing, and clear each line as it's redrawn
+	if p.drawn > 0 {
+		fmt.Fprintf(p.w, "\033[%dA", p.drawn)
+	}
+	for _,line := range lines {
+		fmt.Fprintf(p.w, "\r\033[K%v\n", line)
+	}
+	p.drawn = len(lines)
+}
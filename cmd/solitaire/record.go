@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"solitaire/agent"
+	"solitaire/game"
+	"solitaire/deck"
+	"solitaire/solver"
+)
+
+// A played game, as written by play --record and read by replay. The moves are
+// solver.Moves, since they can say how many cards a tableau move takes.
+type gameRecord struct {
+	Seed int64 `json:"seed"` // Seeds the strategy too
+	Deal string `json:"deal,omitempty"` // The deal's code, if it was given as one rather than by Seed
+	Rules string `json:"rules"`
+	Strategy string `json:"strategy,omitempty"` // Empty if a person played
+	Won bool `json:"won"`
+	Moves []solver.Move `json:"moves"`
+	Certificate []solver.Move `json:"certificate,omitempty"` // A winning line, for play --winnable
+}
+
+// The move agent.Moves index `moveID` stands for in `g`, before it is played
+func recordedMove(g *game.Game, moves *agent.Moves, moveID int) solver.Move {
+	m := moves.At(moveID)
+	rec := solver.Move{Kind: m.Kind, Src: m.Src, Dst: m.Dst}
+	if m.Kind == agent.Tableau {
+		rec.N = len(g.VisibleQueues[m.Src]) // Agents always move the whole queue
+	}
+	return rec
+}
+
+func writeRecord(path string, rec gameRecord) error {
+	data,err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func readRecord(path string) (gameRecord,error) {
+	var rec gameRecord
+	data,err := os.ReadFile(path)
+	if err != nil {
+		return rec,err
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return rec,fmt.Errorf("%v: %w", path, err)
+	}
+	return rec,nil
+}
+
+func (rec gameRecord) deck() (deck.Deck,error) {
+	return flagDeck(rec.Seed, rec.Deal)
+}
+
+func (rec gameRecord) name() string {
+	return dealName(rec.Seed, rec.Deal)
+}
+
+// The game at the end of a record, checking every move is legal
+func finalState(rec gameRecord) (*game.Game,error) {
+	rules,err := game.ParseRules(rec.Rules)
+	if err != nil {
+		return nil,err
+	}
+	d,err := rec.deck()
+	if err != nil {
+		return nil,err
+	}
+	g := game.NewGameWithRules(d, rules)
+	for i,m := range rec.Moves {
+		if err := solver.Apply(g, m); err != nil {
+			return nil,fmt.Errorf("move %v (%v) is illegal: %w", i+1, m, err)
+		}
+	}
+	return g,nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"solitaire/game"
+	"solitaire/ioutils"
+	"solitaire/solver"
+)
+
+func runReplay(args []string) error {
+	fs := newFlagSet("replay")
+	out := addFormatFlag(fs)
+	step := fs.Bool("step", false, "wait for Enter after each move")
+	quiet := fs.Bool("quiet", false, "only check the game, don't show it")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: solitaire replay [flags] <record.json>")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args, true); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError{fmt.Errorf("expected one game record, got %v arguments", fs.NArg())}
+	}
+
+	rec,err := readRecord(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	rules,err := game.ParseRules(rec.Rules)
+	if err != nil {
+		return err
+	}
+	show := !*quiet && *out != "json"
+	d,err := rec.deck()
+	if err != nil {
+		return err
+	}
+	g := game.NewGameWithRules(d, rules)
+	if show {
+		g.Display(true)
+	}
+	for i,m := range rec.Moves {
+		if err := solver.Apply(g, m); err != nil {
+			return fmt.Errorf("move %v (%v) is illegal: %w", i+1, m, err)
+		}
+		if show {
+			fmt.Printf("Move %v: %v\n", i+1, m)
+			g.Display(true)
+			if *step {
+				ioutils.Input("")
+			}
+		}
+	}
+	if g.IsWon() != rec.Won {
+		return fmt.Errorf("the record says won=%v, but the replay gives won=%v", rec.Won, g.IsWon())
+	}
+
+	summary := struct {
+		Seed int64 `json:"seed"`
+		Deal string `json:"deal,omitempty"`
+		Rules string `json:"rules"`
+		Strategy string `json:"strategy,omitempty"`
+		Won bool `json:"won"`
+		Moves int `json:"moves"`
+	}{rec.Seed, rec.Deal, rec.Rules, rec.Strategy, rec.Won, len(rec.Moves)}
+	err = out.print(summary, func() {
+		fmt.Printf("Replayed %v moves of deal %v, all legal. Won: %v\n", len(rec.Moves), rec.name(), rec.Won)
+	})
+	if err == nil && !rec.Won {
+		return exitStatus(exitLost)
+	}
+	return err
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"solitaire/experiment"
+	"solitaire/registry"
+)
+
+func addStoreFlag(fs *flag.FlagSet) *string {
+	return fs.String("store", experiment.DefaultStoreDir(), "results store to add the run to, empty for none (default $SOLITAIRE_RESULTS or ./results)")
+}
+
+// Add a finished run to the store in `dir`, if there is one
+func storeRun(dir string, m experiment.Manifest, sum experiment.Summary) error {
+	if dir == "" {
+		return nil
+	}
+	store,err := experiment.OpenStore(dir)
+	if err != nil {
+		return err
+	}
+	_,err = store.Append(m, sum)
+	return err
+}
+
+const resultsUsage = `Usage: solitaire results [flags] [view]
+
+Views:
+  list         every matching run, oldest first (the default)
+  leaderboard  strategies and parameter sets by win rate, over all their runs (each deal once)
+  history      win rate of each run over time, for one --strategy
+  best         the best parameter sets seen for each strategy
+
+Flags:`
+
+func runResults(args []string) error {
+	fs := newFlagSet("results")
+	dir := fs.String("store", experiment.DefaultStoreDir(), "results store (default $SOLITAIRE_RESULTS or ./results)")
+	var filter experiment.Filter
+	fs.StringVar(&filter.Strategy, "strategy", "", "only runs of this strategy")
+	fs.StringVar(&filter.Rules, "rules", "", "only runs with these rules, e.g. draw3")
+	fs.StringVar(&filter.Command, "command", "", "only runs of this command, e.g. sim")
+	var params registry.ParamFlag
+	fs.Var(&params, "param", "only runs with this strategy parameter, as NAME=VALUE, can be repeated")
+	since := fs.String("since", "", "only runs started on or after this date (2006-01-02 or RFC 3339)")
+	until := fs.String("until", "", "only runs started before this date")
+	minGames := fs.Int("min-games", 0, "leaderboard and best: leave out parameter sets with fewer games")
+	top := fs.Int("top", 10, "leaderboard and best: rows to show")
+	out := addFormatFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), resultsUsage)
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args, true); err != nil {
+		return err
+	}
+	view := "list"
+	if fs.NArg() > 1 {
+		return usageError{fmt.Errorf("expected at most one view, got %v", fs.Args())}
+	} else if fs.NArg() == 1 {
+		view = fs.Arg(0)
+	}
+
+	if *top < 1 {
+		return usageError{fmt.Errorf("--top must be at least 1, got %v", *top)}
+	}
+
+	var err error
+	if filter.Params,err = registry.ParseParams(params); err != nil {
+		return usageError{err}
+	}
+	if filter.Since,err = parseDate(*since); err != nil {
+		return usageError{err}
+	}
+	if filter.Until,err = parseDate(*until); err != nil {
+		return usageError{err}
+	}
+	store,err := experiment.OpenStore(*dir)
+	if err != nil {
+		return err
+	}
+	entries,err := store.Query(filter)
+	if err != nil {
+		return err
+	}
+
+	switch view {
+	case "list":
+		return out.print(entries, func() { printRuns(entries) })
+	case "history":
+		if filter.Strategy == "" {
+			return usageError{fmt.Errorf("history needs --strategy")}
+		}
+		return out.print(entries, func() { printHistory(entries) })
+	case "leaderboard":
+		groups := groupRuns(entries, *minGames)
+		groups = groups[:min(len(groups), *top)]
+		return out.print(groups, func() { printGroups(groups) })
+	case "best":
+		var best []runGroup
+		perStrategy := map[string]int{}
+		for _,g := range groupRuns(entries, *minGames) {
+			if perStrategy[g.Strategy] < *top {
+				perStrategy[g.Strategy]++
+				best = append(best, g)
+			}
+		}
+		slices.SortStableFunc(best, func(a, b runGroup) int { return strings.Compare(a.Strategy, b.Strategy) })
+		return out.print(best, func() { printGroups(best) })
+	}
+	return usageError{fmt.Errorf("unknown view %q, expected list, leaderboard, history or best", view)}
+}
+
+func parseDate(s string) (time.Time,error) {
+	if s == "" {
+		return time.Time{},nil
+	}
+	if t,err := time.Parse("2006-01-02", s); err == nil {
+		return t,nil
+	}
+	t,err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t,fmt.Errorf("bad date %q, expected 2006-01-02 or RFC 3339", s)
+	}
+	return t,nil
+}
+
+// Parameters as NAME=VALUE,NAME=VALUE, sorted by name
+func paramString(params map[string]string) string {
+	var pairs []string
+	for _,k := range slices.Sorted(maps.Keys(params)) {
+		pairs = append(pairs, k + "=" + params[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func printRuns(entries []experiment.Entry) {
+	fmt.Printf("%-22v %-8v %-14v %-8v %7v %8v  %v\n", "id", "command", "strategy", "rules", "games", "won", "params")
+	for _,e := range entries {
+		m, s := e.Manifest, e.Summary
+		fmt.Printf("%-22v %-8v %-14v %-8v %7v %7.2f%%  %v\n", e.ID, m.Command, m.Strategy, m.Rules, s.Games, 100*s.WinRate, paramString(m.Params))
+	}
+}
+
+func printHistory(entries []experiment.Entry) {
+	fmt.Printf("%-17v %-8v %7v %8v %-17v\n", "started", "revision", "games", "won", "95% CI")
+	for _,e := range entries {
+		m, s := e.Manifest, e.Summary
+		rev := m.Revision[:min(len(m.Revision), 7)]
+		if m.Modified {
+			rev += "+"
+		}
+		ci := fmt.Sprintf("%.2f-%.2f%%", 100*s.CILow, 100*s.CIHigh)
+		fmt.Printf("%-17v %-8v %7v %7.2f%% %-17v %v\n", m.Start.Local().Format("2006-01-02 15:04"), rev, s.Games, 100*s.WinRate, ci,
+			strings.Repeat("#", int(200*s.WinRate + 0.5))) // A # for every half percent
+	}
+}
+
+// Runs of the same strategy, parameters and rules, pooled. Each deal counts once: a run
+// over seeds already counted replays the same deals, so it would only shrink the CI.
+type runGroup struct {
+	Strategy string `json:"strategy"`
+	Params map[string]string `json:"params"`
+	Rules string `json:"rules"`
+	Runs int `json:"runs"` // Pooled; Repeats aren't counted
+	Repeats int `json:"repeats"` // Runs left out, for seeds a newer run has
+	Games int `json:"games"`
+	Wins int `json:"wins"`
+	WinRate float64 `json:"win_rate"`
+	CILow float64 `json:"ci_low"`
+	CIHigh float64 `json:"ci_high"`
+	Last time.Time `json:"last"`
+
+	seeds [][2]int64 // Seed ranges counted, [first, end)
+}
+
+// Best first
+func groupRuns(entries []experiment.Entry, minGames int) []runGroup {
+	byKey := map[string]*runGroup{}
+	var keys []string
+	// Newest first, so that where runs overlap the one with the latest code counts
+	for _,e := range slices.Backward(entries) {
+		m := e.Manifest
+		key := m.Strategy + "|" + paramString(m.Params) + "|" + m.Rules
+		g,ok := byKey[key]
+		if !ok {
+			g = &runGroup{Strategy: m.Strategy, Params: m.Params, Rules: m.Rules}
+			byKey[key] = g
+			keys = append(keys, key)
+		}
+		first,end := m.FirstSeed, m.FirstSeed + int64(m.Games)
+		if slices.ContainsFunc(g.seeds, func(r [2]int64) bool { return first < r[1] && r[0] < end }) {
+			g.Repeats++
+			continue
+		}
+		g.seeds = append(g.seeds, [2]int64{first, end})
+		g.Runs++
+		g.Games += e.Summary.Games
+		g.Wins += e.Summary.Wins
+		if m.Start.After(g.Last) {
+			g.Last = m.Start
+		}
+	}
+	var groups []runGroup
+	for _,key := range keys {
+		g := byKey[key]
+		if g.Games < max(minGames, 1) {
+			continue
+		}
+		g.WinRate = float64(g.Wins) / float64(g.Games)
+		g.CILow,g.CIHigh = experiment.Wilson(g.Wins, g.Games, 1.96)
+		groups = append(groups, *g)
+	}
+	slices.SortStableFunc(groups, func(a, b runGroup) int { return cmp.Compare(b.WinRate, a.WinRate) })
+	return groups
+}
+
+func printGroups(groups []runGroup) {
+	fmt.Printf("%8v %-17v %7v %5v  %-14v %-8v %v\n", "won", "95% CI", "games", "runs", "strategy", "rules", "params")
+	for _,g := range groups {
+		ci := fmt.Sprintf("%.2f-%.2f%%", 100*g.CILow, 100*g.CIHigh)
+		fmt.Printf("%7.2f%% %-17v %7v %5v  %-14v %-8v %v\n", 100*g.WinRate, ci, g.Games, g.Runs, g.Strategy, g.Rules, paramString(g.Params))
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"time"
+
+	"solitaire/agent"
+	"solitaire/experiment"
+	"solitaire/sim"
+)
+
+func runSim(args []string) error {
+	fs := newFlagSet("sim")
+	strategy := addStrategyFlags(fs, "probabilistic")
+	rules := addRulesFlag(fs)
+	seed := addSeedFlag(fs, "seed of the first deal, the rest follow on")
+	out := addFormatFlag(fs)
+	games := fs.Int("games", 1000, "number of games")
+	workers := fs.Int("workers", runtime.NumCPU(), "games played in parallel")
+	dir := fs.String("out", "", "write a manifest, a row per game and a summary to this directory")
+	rowFormat := fs.String("rows", "csv", "format of the rows in --out, csv or jsonl")
+	store := addStoreFlag(fs)
+	progressMode := addProgressFlag(fs)
+	every := fs.Duration("checkpoint", 30*time.Second, "with --out, how often to save progress; interrupted runs carry on from the last save")
+	if err := parseFlags(fs, args, false); err != nil {
+		return err
+	}
+
+	strat,err := strategy.build()
+	if err != nil {
+		return err
+	}
+	params,err := strategy.resolved()
+	if err != nil {
+		return err
+	}
+	format,err := experiment.ParseFormat(*rowFormat)
+	if err != nil {
+		return usageError{err}
+	}
+	if err := checkProgressMode(*progressMode); err != nil {
+		return err
+	}
+	manifest := experiment.NewManifest("sim", *strategy.name, params, *rules, *seed, *games)
+	var run *experiment.Run
+	summary := &experiment.Summary{}
+	done := 0
+	finished := false // Already, by an earlier session, so already stored too
+	var stop <-chan struct{}
+	if *dir != "" {
+		if run,err = experiment.Start(*dir, manifest, format); err != nil {
+			return err
+		}
+		summary = &run.Summary
+		if done = run.Done(); done > 0 {
+			fmt.Fprintf(os.Stderr, "Resuming %v: %v of %v games already done\n", *dir, done, *games)
+		}
+		finished = done == *games
+		// Interrupting checkpoints the run, so that the same command carries on with it
+		ctx,cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		stop = ctx.Done()
+	}
+
+	start := time.Now()
+	lastCheckpoint := start
+	seeds := sim.SeedRange(*seed + int64(done), *games - done)
+	prog := newProgress(*progressMode, fmt.Sprintf("sim %v, %v", strategy.spec(), rules), *games, *summary)
+	prog.run()
+	var addErr error
+	sim.Stream(strat, seeds, *workers, sim.Options{Rules: *rules}, stop, func(i int, r sim.GameResult) {
+		row := experiment.NewRow(seeds[i], r)
+		prog.add(row)
+		done++
+		if run == nil {
+			summary.Add(row)
+			return
+		}
+		if addErr == nil {
+			addErr = run.Add(row)
+		}
+		if addErr == nil && time.Since(lastCheckpoint) >= *every {
+			addErr = run.Checkpoint()
+			lastCheckpoint = time.Now()
+		}
+	})
+	prog.stop()
+	if addErr != nil {
+		return addErr
+	}
+	elapsed := time.Since(start)
+	if run != nil {
+		if err := run.Close(); err != nil {
+			return err
+		}
+		manifest = run.Manifest
+	} else {
+		summary.Seconds = elapsed.Seconds()
+		manifest.End = time.Now().UTC()
+	}
+	if done < *games {
+		fmt.Fprintf(os.Stderr, "Interrupted after %v of %v games, checkpointed in %v. Run the same command again to carry on.\n",
+			done, *games, run.Dir)
+		return exitStatus(exitInterrupted)
+	}
+	if !finished {
+		if err := storeRun(*store, manifest, *summary); err != nil {
+			return err
+		}
+	}
+
+	report := struct {
+		Manifest experiment.Manifest `json:"manifest"`
+		Summary experiment.Summary `json:"summary"`
+	}{manifest, *summary}
+	return out.print(report, func() {
+		fmt.Printf("Won %v of %v games (%.2f%%, 95%% CI %.2f-%.2f%%) in %v\n", summary.Wins, summary.Games,
+			100*summary.WinRate, 100*summary.CILow, 100*summary.CIHigh, elapsed.Round(time.Millisecond))
+		printMetrics(*summary)
+		if run != nil {
+			fmt.Printf("Results in %v\n", run.Dir)
+		}
+	})
+}
+
+// What a summary says beyond the win rate
+func printMetrics(s experiment.Summary) {
+	var kinds []string
+	for kind := range agent.NMoveKinds {
+		name := agent.MoveKind(kind).String()
+		kinds = append(kinds, fmt.Sprintf("%v %.1f", name, s.MeanByKind[name]))
+	}
+	fmt.Printf("Per game: %.1f moves (%v), %.2f passes\n", s.MeanMoves, strings.Join(kinds, ", "), s.MeanPasses)
+	fmt.Printf("          %.1f moves without progress, the last progress at move %.1f\n", s.MeanNoProgress, s.MeanStalledAt)
+	fmt.Printf("Lost games: %.1f cards on the foundation, %.1f revealed\n", s.LostMeanFoundation, s.LostMeanRevealed)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"solitaire/deal"
+	"solitaire/game"
+	"solitaire/solver"
+)
+
+func runSolve(args []string) error {
+	fs := newFlagSet("solve")
+	rules := addRulesFlag(fs)
+	seed := addSeedFlag(fs, "the deal")
+	code := addDealFlag(fs)
+	out := addFormatFlag(fs)
+	maxNodes := fs.Int("max-nodes", 1000000, "positions to search before giving up")
+	showMoves := fs.Bool("moves", false, "list the winning moves")
+	if err := parseFlags(fs, args, false); err != nil {
+		return err
+	}
+
+	d,err := flagDeck(*seed, *code)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	r := solver.Solve(game.NewGameWithRules(d, *rules), *maxNodes)
+	elapsed := time.Since(start)
+
+	summary := struct {
+		Seed int64 `json:"seed"`
+		Deal string `json:"deal"`
+		Rules string `json:"rules"`
+		Status string `json:"status"`
+		Nodes int `json:"nodes"`
+		Seconds float64 `json:"seconds"`
+		Moves []solver.Move `json:"moves,omitempty"`
+	}{*seed, deal.Code(d), rules.String(), r.Status.String(), r.Nodes, elapsed.Seconds(), r.Moves}
+	err = out.print(summary, func() {
+		fmt.Printf("Deal %v, %v: %v after %v positions in %v\n",
+			dealName(*seed, *code), rules, r.Status, r.Nodes, elapsed.Round(time.Millisecond))
+		if r.Status == solver.Won {
+			fmt.Printf("Won in %v moves\n", len(r.Moves))
+			if *showMoves {
+				for i,m := range r.Moves {
+					fmt.Printf("%4v. %v\n", i+1, m)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return solveStatus(r.Status)
+}
+
+// The exit status for a solver verdict
+func solveStatus(s solver.Status) error {
+	switch s {
+	case solver.Unwinnable:
+		return exitStatus(exitLost)
+	case solver.Unknown:
+		return exitStatus(exitUnknown)
+	}
+	return nil
+}
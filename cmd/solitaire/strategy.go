@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"solitaire/agent"
+	"solitaire/dsl"
+	"solitaire/registry"
+)
+
+type strategyFlags struct {
+	name *string
+	params registry.ParamFlag
+}
+
+func addStrategyFlags(fs *flag.FlagSet, def string) *strategyFlags {
+	s := &strategyFlags{}
+	s.name = fs.String("strategy", def, "strategy to play with, see solitaire strategies")
+	fs.Var(&s.params, "param", "strategy parameter as NAME=VALUE, can be repeated")
+	return s
+}
+
+func (s *strategyFlags) build() (agent.Strategy,error) {
+	values,err := registry.ParseParams(s.params)
+	if err != nil {
+		return nil,usageError{err}
+	}
+	strategy,err := registry.New(*s.name, values)
+	if err != nil {
+		return nil,strategyError(err)
+	}
+	return strategy,nil
+}
+
+// A usage error if the strategy or its parameters were given wrongly, but not if it
+// couldn't be built from them, e.g. because a weights file is missing
+func strategyError(err error) error {
+	if errors.As(err, new(registry.ParamError)) {
+		return usageError{err}
+	}
+	return err
+}
+
+// Every parameter of the strategy, defaults included, for manifests
+func (s *strategyFlags) resolved() (map[string]string,error) {
+	values,err := registry.ParseParams(s.params)
+	if err != nil {
+		return nil,usageError{err}
+	}
+	all,err := registry.Resolve(*s.name, values)
+	if err != nil {
+		return nil,usageError{err}
+	}
+	return all,nil
+}
+
+// The strategy as one string, the form registry.ParseSpec reads, for output and records
+func (s *strategyFlags) spec() string {
+	if len(s.params) == 0 {
+		return *s.name
+	}
+	return *s.name + ":" + strings.Join(s.params, ",")
+}
+
+func runStrategies(args []string) error {
+	fs := newFlagSet("strategies")
+	ruleNames := fs.Bool("rule-names", false, "list what rules files can refer to instead")
+	if err := parseFlags(fs, args, false); err != nil {
+		return err
+	}
+	if *ruleNames {
+		for _,name := range dsl.Names() {
+			fmt.Println(name)
+		}
+		return nil
+	}
+	for _,e := range registry.All() {
+		fmt.Printf("%v: %v\n", e.Name, e.Doc)
+		for _,p := range e.Params {
+			def := p.Default
+			if def == "" {
+				def = "none"
+			}
+			fmt.Printf("    %-16v %v (default %v)\n", p.Name, p.Doc, def)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"solitaire/corpus"
+	"solitaire/deck"
+	"solitaire/game"
+	"solitaire/pimc"
+	"solitaire/registry"
+	"solitaire/sim"
+	"solitaire/solver"
+)
+
+// How a deal went for each player in the winnability experiment
+type dealOutcome struct {
+	agent sim.GameResult
+	pimc *sim.GameResult // nil if PIMC didn't play this deal
+	solver solver.Status
+}
+
+// What the experiment finds for one rule set. The fraction of deals that can be won
+// without seeing the cards is at least what the agent wins. The solver sees them, so
+// what it wins is more than a player can hope for, but its search is pruned and has a
+// limit, so neither what it wins nor that plus what it gave up on is an upper bound.
+type winnability struct {
+	Rules string `json:"rules"`
+	Deals int `json:"deals"`
+	Agent fraction `json:"agent"` // Lower bound
+	AgentTooLong int `json:"agent_too_long"` // Games stopped at sim.MaxMoves
+	PIMC *fraction `json:"pimc,omitempty"` // Also a lower bound, on the deals it played
+	PIMCTooLong int `json:"pimc_too_long,omitempty"`
+	PIMCFallbacks float64 `json:"pimc_fallbacks,omitempty"` // Of its moves, the share no sampled world was won in time for
+	PIMCUnknown float64 `json:"pimc_unknown,omitempty"` // Of the worlds it sampled, the share its solver gave up on
+	Solver fraction `json:"solver"` // Thoughtful: won with every card known
+	MaxNodes int `json:"max_nodes"` // The solver's limit: --max-nodes, or the corpus's for its labels
+	SolverUnknown int `json:"solver_unknown"` // Out of budget
+	SolverWonOrUnknown fraction `json:"solver_won_or_unknown"` // Won, or gave up on. Deals it calls unwinnable may not be.
+	WonUnwinnable int `json:"won_unwinnable"` // Won by a player though the solver says unwinnable; should be 0
+}
+
+// A comma-separated list of rule sets
+type rulesListFlag []game.Rules
+
+func (l *rulesListFlag) String() string {
+	var names []string
+	for _,r := range *l {
+		names = append(names, r.String())
+	}
+	return strings.Join(names, ",")
+}
+
+func (l *rulesListFlag) Set(s string) error {
+	*l = nil
+	for _,name := range strings.Split(s, ",") {
+		r,err := game.ParseRules(name)
+		if err != nil {
+			return err
+		}
+		*l = append(*l, r)
+	}
+	return nil
+}
+
+func runWinnability(args []string) error {
+	fs := newFlagSet("winnability")
+	rulesList := rulesListFlag{game.DefaultRules, {NFlip: 1}}
+	fs.Var(&rulesList, "rules", "rule sets to run, comma-separated (default draw3,draw1)")
+	seed := fs.Int64("seed", 4_000_000, "seed of the first deal, the rest follow on")
+	games := fs.Int("games", 500, "deals for each rule set")
+	version := fs.String("corpus", "", "take the deals, and the solver's labels for them, from this shipped corpus instead of --seed (see solitaire corpus)")
+	agentSpec := fs.String("agent", "expectimax:Eval=reachable_waste", "the best agent, as NAME:K=V,K=V (see solitaire strategies)")
+	maxNodes := fs.Int("max-nodes", 500000, "positions the solver searches per deal")
+	pimcGames := fs.Int("pimc-games", 0, "deals for PIMC to play too, the first of each rule set's; it's slow, so 0 by default")
+	pimcSamples := fs.Int("pimc-samples", pimc.Default.Samples, "worlds PIMC solves at each move")
+	pimcNodes := fs.Int("pimc-nodes", pimc.Default.MaxNodes, "positions PIMC's solver searches in each world")
+	workers := fs.Int("workers", runtime.NumCPU(), "deals played in parallel")
+	out := addFormatFlag(fs)
+	if err := parseFlags(fs, args, false); err != nil {
+		return err
+	}
+
+	strat,err := registry.NewFromSpec(*agentSpec)
+	if err != nil {
+		return strategyError(err)
+	}
+	var stats pimc.Stats
+	fallback,err := registry.New("stock", nil)
+	if err != nil {
+		return err
+	}
+	pimcStrat := pimc.Strategy{Samples: *pimcSamples, MaxNodes: *pimcNodes, Fallback: fallback, Stats: &stats}.Strategy()
+
+	var results []winnability
+	start := time.Now()
+	for _,rules := range rulesList {
+		// The deals, their seeds (which seed the players' random sources) and, from a
+		// corpus, their labels
+		decks := make([]deck.Deck, *games)
+		seeds := sim.SeedRange(*seed, *games)
+		var labels []solver.Status
+		limit := *maxNodes
+		if *version != "" {
+			c,err := corpus.Load(*version, rules)
+			if err != nil {
+				return err
+			}
+			if len(c.Entries) < *games {
+				return usageError{fmt.Errorf("corpus %v has only %v deals for %v", *version, len(c.Entries), rules)}
+			}
+			limit = c.MaxNodes
+			for i,e := range c.Entries[:*games] {
+				if decks[i],err = e.Deck(); err != nil {
+					return err
+				}
+				seeds[i] = e.Seed
+				labels = append(labels, e.Status)
+			}
+		} else {
+			for i,s := range seeds {
+				decks[i] = sim.Deal(s)
+			}
+		}
+
+		stats = pimc.Stats{}
+		opts := sim.Options{Rules: rules}
+		w := winnability{Rules: rules.String(), Deals: *games, MaxNodes: limit}
+		var agentWins, pimcWins, pimcPlayed, solverWins int
+		logged := time.Now()
+		sim.Ordered(*games, *workers, nil, func(i int) dealOutcome {
+			o := dealOutcome{agent: sim.PlayDeck(strat, decks[i], seeds[i], opts)}
+			if i < *pimcGames {
+				r := sim.PlayDeck(pimcStrat, decks[i], seeds[i], opts)
+				o.pimc = &r
+			}
+			if labels != nil {
+				o.solver = labels[i]
+			} else {
+				o.solver = solver.Solve(game.NewGameWithRules(decks[i], rules), *maxNodes).Status
+			}
+			return o
+		}, func(i int, o dealOutcome) {
+			won := o.agent.Won
+			if o.agent.Won {
+				agentWins++
+			}
+			if o.agent.End == sim.TooLong {
+				w.AgentTooLong++
+			}
+			if o.pimc != nil {
+				pimcPlayed++
+				won = won || o.pimc.Won
+				if o.pimc.Won {
+					pimcWins++
+				}
+				if o.pimc.End == sim.TooLong {
+					w.PIMCTooLong++
+				}
+			}
+			switch o.solver {
+			case solver.Won:
+				solverWins++
+			case solver.Unknown:
+				w.SolverUnknown++
+			case solver.Unwinnable:
+				if won {
+					w.WonUnwinnable++
+				}
+			}
+			if time.Since(logged) > logEvery {
+				logged = time.Now()
+				fmt.Fprintf(os.Stderr, "%v: %v/%v deals in %v\n", rules, i+1, *games, time.Since(start).Round(time.Second))
+			}
+		})
+		w.Agent = newFraction(agentWins, *games)
+		w.Solver = newFraction(solverWins, *games)
+		w.SolverWonOrUnknown = newFraction(solverWins + w.SolverUnknown, *games)
+		if pimcPlayed > 0 {
+			f := newFraction(pimcWins, pimcPlayed)
+			w.PIMC = &f
+			w.PIMCFallbacks = float64(stats.Fallbacks.Load()) / float64(max(stats.Moves.Load(), 1))
+			w.PIMCUnknown = float64(stats.Unknown.Load()) / float64(max(stats.Worlds.Load(), 1))
+		}
+		results = append(results, w)
+	}
+
+	report := struct {
+		Agent string `json:"agent"`
+		Corpus string `json:"corpus,omitempty"`
+		FirstSeed int64 `json:"first_seed,omitempty"`
+		PIMC string `json:"pimc,omitempty"`
+		Seconds float64 `json:"seconds"`
+		Rules []winnability `json:"rules"`
+	}{Agent: *agentSpec, Corpus: *version, Seconds: time.Since(start).Seconds(), Rules: results}
+	if *version == "" {
+		report.FirstSeed = *seed
+	}
+	if *pimcGames > 0 {
+		report.PIMC = fmt.Sprintf("%v samples of %v positions, first %v deals", *pimcSamples, *pimcNodes, *pimcGames)
+	}
+	return out.print(report, func() {
+		// Markdown, to paste into notes.md
+		deals := fmt.Sprintf("deals from seed %v", *seed)
+		if *version != "" {
+			deals = "corpus " + *version
+		}
+		// One limit, unless corpora for different rules were labelled with different ones
+		var limits []string
+		same := true
+		for _,w := range results {
+			limits = append(limits, fmt.Sprintf("%v for %v", w.MaxNodes, w.Rules))
+			same = same && w.MaxNodes == results[0].MaxNodes
+		}
+		limit := strings.Join(limits, ", ")
+		if same && len(results) > 0 {
+			limit = strconv.Itoa(results[0].MaxNodes)
+		}
+		fmt.Printf("Agent %v, solver limit %v positions, %v, %v:\n\n", *agentSpec, limit, deals, time.Duration(report.Seconds * float64(time.Second)).Round(time.Second))
+		fmt.Println("| rules | deals | agent (lower bound) | PIMC | solver won | solver gave up | won + gave up (pruned search, not a proof) |")
+		fmt.Println("|---|---|---|---|---|---|---|")
+		pct := func(f fraction) string {
+			return fmt.Sprintf("%.1f%% (%.1f-%.1f)", 100*f.Rate, 100*f.Lo, 100*f.Hi)
+		}
+		for _,w := range results {
+			p := "-"
+			if w.PIMC != nil {
+				p = fmt.Sprintf("%v on %v", pct(*w.PIMC), w.PIMC.Of)
+			}
+			fmt.Printf("| %v | %v | %v | %v | %v | %v | %v |\n", w.Rules, w.Deals, pct(w.Agent), p, pct(w.Solver), w.SolverUnknown, pct(w.SolverWonOrUnknown))
+		}
+		fmt.Println("\nIntervals are 95% (Wilson).")
+		for _,w := range results {
+			if w.AgentTooLong + w.PIMCTooLong > 0 {
+				fmt.Printf("%v: games stopped at %v moves: %v agent, %v PIMC\n", w.Rules, sim.MaxMoves, w.AgentTooLong, w.PIMCTooLong)
+			}
+			if w.PIMC != nil {
+				fmt.Printf("%v: PIMC's solver gave up on %.1f%% of the worlds it sampled, and for %.1f%% of its moves none was won, so stock chose\n",
+					w.Rules, 100*w.PIMCUnknown, 100*w.PIMCFallbacks)
+			}
+			if w.WonUnwinnable > 0 {
+				fmt.Printf("%v: %v deals the solver calls unwinnable were won! Its search is incomplete, see the solver package\n", w.Rules, w.WonUnwinnable)
+			}
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+
+	"solitaire/deal"
+	"solitaire/game"
+)
+
+// Flags for picking a winnable deal, shared by winnable and play --winnable
+type winnableFlags struct {
+	target deal.Target
+	maxNodes *int
+	tries *int
+	model *string
+}
+
+func addWinnableFlags(fs *flag.FlagSet) *winnableFlags {
+	w := &winnableFlags{target: deal.Targets["any"]}
+	fs.Func("difficulty", "any, easy, medium, hard, or a range like 0.2-0.5 (default any)", func(s string) error {
+		t,err := deal.ParseTarget(s)
+		w.target = t
+		return err
+	})
+	w.maxNodes = fs.Int("max-nodes", 200000, "positions the solver searches per deal; deals it can't settle are skipped")
+	w.tries = fs.Int("tries", 1000, "deals to try for each winnable one before giving up")
+	w.model = addModelFlag(fs)
+	return w
+}
+
+func (w *winnableFlags) find(rng *rand.Rand, rules game.Rules) (deal.Certified,error) {
+	model,err := loadModel(*w.model)
+	if err != nil {
+		return deal.Certified{},err
+	}
+	return deal.FindWinnable(rng, rules, model, w.target, *w.maxNodes, *w.tries)
+}
+
+// A deal from `path` (or a new one, if empty) with the rules and difficulty asked for
+func (w *winnableFlags) pick(path string, rules game.Rules) (deal.Certified,error) {
+	rng := rand.New(rand.NewSource(rand.Int63()))
+	if path == "" {
+		return w.find(rng, rules)
+	}
+	deals,err := deal.ReadCertified(path)
+	if err != nil {
+		return deal.Certified{},err
+	}
+	var matching []deal.Certified
+	for _,c := range deals {
+		if c.Rules == rules.String() && w.target.Contains(c.Difficulty) {
+			matching = append(matching, c)
+		}
+	}
+	if len(matching) == 0 {
+		return deal.Certified{},fmt.Errorf("no %v deals with difficulty %v-%v in %v", rules, w.target.Min, w.target.Max, path)
+	}
+	c := matching[rng.Intn(len(matching))]
+	return c,c.Verify()
+}
+
+func runWinnable(args []string) error {
+	fs := newFlagSet("winnable")
+	rules := addRulesFlag(fs)
+	w := addWinnableFlags(fs)
+	count := fs.Int("count", 1, "deals to generate")
+	seed := addSeedFlag(fs, "seeds the choice of deals, so the same flags give the same deals")
+	out := addFormatFlag(fs)
+	save := fs.String("save", "", "append the deals and their certificates to this file, for play --deals")
+	check := fs.String("check", "", "instead of generating, check every certificate in this file")
+	if err := parseFlags(fs, args, false); err != nil {
+		return err
+	}
+
+	if *check != "" {
+		deals,err := deal.ReadCertified(*check)
+		if err != nil {
+			return err
+		}
+		for _,c := range deals {
+			if err := c.Verify(); err != nil {
+				return err
+			}
+		}
+		return out.print(struct {
+			Checked int `json:"checked"`
+		}{len(deals)}, func() {
+			fmt.Printf("All %v certificates in %v win\n", len(deals), *check)
+		})
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+	var deals []deal.Certified
+	for range *count {
+		c,err := w.find(rng, *rules)
+		if err != nil {
+			return err
+		}
+		if *out == "text" {
+			fmt.Printf("Deal %v, difficulty %.2f, won in %v moves\n", c.Deal, c.Difficulty, len(c.Moves))
+		}
+		deals = append(deals, c)
+	}
+	if *save != "" {
+		if err := deal.AppendCertified(*save, deals); err != nil {
+			return err
+		}
+	}
+	return out.print(deals, func() {
+		if *save != "" {
+			fmt.Printf("Saved to %v\n", *save)
+		}
+	})
+}
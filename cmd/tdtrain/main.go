@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+
+	"solitaire/agent"
+	"solitaire/sim"
+	"solitaire/td"
+)
+
+// Train a value function by TD(λ) self-play and save its weights.
+// Play with them using solitaire play --strategy td --param Weights=<weights>.
+func main() {
+	out := flag.String("out", "td_weights.json", "where to save the weights")
+	init := flag.String("init", "", "continue training from these weights")
+	hidden := flag.Int("hidden", 0, "hidden units, 0 for a linear value function")
+	episodes := flag.Int("episodes", 20000, "training games")
+	firstSeed := flag.Int64("train-seed", 0, "seed of the first training deal")
+	alpha := flag.Float64("alpha", 0.01, "learning rate")
+	lambda := flag.Float64("lambda", 0.7, "trace decay")
+	epsilon := flag.Float64("epsilon", 0.05, "exploration rate while training")
+	evalEvery := flag.Int("eval-every", 2000, "episodes between evaluations")
+	nEval := flag.Int("eval-games", 2000, "games per evaluation")
+	evalSeed := flag.Int64("eval-seed", 1_000_000, "seed of the first evaluation deal")
+	seed := flag.Int64("seed", 1, "seed for initialization and exploration")
+	flag.Parse()
+
+	var net *agent.ValueNet
+	if *init != "" {
+		var err error
+		net,err = agent.LoadValueNet(*init)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else {
+		net = agent.NewValueNet(*hidden, rand.New(rand.NewSource(*seed)))
+	}
+
+	td.Train(net, td.Config{
+		Episodes: *episodes,
+		FirstSeed: *firstSeed,
+		Alpha: *alpha,
+		Lambda: *lambda,
+		Epsilon: *epsilon,
+		EvalEvery: *evalEvery,
+		EvalSeeds: sim.SeedRange(*evalSeed, *nEval),
+		Workers: runtime.NumCPU(),
+		Seed: *seed,
+	})
+
+	if err := net.Save(*out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println("Saved weights to", *out)
+}
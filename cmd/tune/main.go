@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+
+	"solitaire/registry"
+	"solitaire/sim"
+	"solitaire/tune"
+)
+
+// Tune the numeric parameters of a strategy from the registry. Rerun with the same -out to resume.
+func main() {
+	strategy := flag.String("strategy", "probabilistic", "strategy to tune, see solitaire strategies")
+	var params registry.ParamFlag
+	flag.Var(&params, "param", "starting value of a parameter, or the value of one that isn't tuned, as NAME=VALUE; can be repeated")
+	out := flag.String("out", "tune_output", "directory for the checkpoint, best parameters and learning curve")
+	generations := flag.Int("generations", 20, "number of generations")
+	population := flag.Int("population", 24, "candidates per generation")
+	elite := flag.Int("elite", 6, "candidates kept unchanged each generation")
+	sigma := flag.Float64("sigma", 0.1, "mutation size, as a fraction of each parameter's range")
+	finalists := flag.Int("finalists", 3, "top candidates checked on the held-out deals")
+	nTrain := flag.Int("train", 2000, "number of training deals")
+	trainStart := flag.Int64("train-seed", 0, "seed of the first training deal")
+	nHoldout := flag.Int("holdout", 10000, "number of held-out deals")
+	holdoutStart := flag.Int64("holdout-seed", 1_000_000, "seed of the first held-out deal")
+	workers := flag.Int("workers", runtime.NumCPU(), "games played in parallel")
+	seed := flag.Int64("seed", 1, "seed for the search itself")
+	flag.Parse()
+
+	values,err := registry.ParseParams(params)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	base,err := registry.Tunable(*strategy, values)
+	if errors.As(err, new(registry.ParamError)) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Ctrl-C stops at the next candidate, with everything so far checkpointed
+	ctx,stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	result,err := tune.Run(tune.Config{
+		Base: base,
+		TrainSeeds: sim.SeedRange(*trainStart, *nTrain),
+		HoldoutSeeds: sim.SeedRange(*holdoutStart, *nHoldout),
+		Population: *population,
+		Elite: *elite,
+		Generations: *generations,
+		Sigma: *sigma,
+		Finalists: *finalists,
+		Workers: *workers,
+		Seed: *seed,
+		OutDir: *out,
+		Stop: ctx.Done(),
+	})
+	if errors.Is(err, tune.ErrStopped) {
+		fmt.Fprintf(os.Stderr, "Interrupted. Rerun with -out %v to carry on.\n", *out)
+		os.Exit(130)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Best parameters:")
+	for i,name := range result.Params {
+		fmt.Printf("\t%v: %v\n", name, result.Values[i])
+	}
+	fmt.Printf("Train win rate: %v, held-out win rate: %v\n", result.TrainWinRate, result.HoldoutWinRate)
+}
@@ -0,0 +1,182 @@
+package corpus
+
+import (
+	"bufio"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"solitaire/deal"
+	"solitaire/deck"
+	"solitaire/game"
+	"solitaire/sim"
+	"solitaire/solver"
+)
+
+// Benchmark deals, labelled by the solver, so that a strategy's win rate can be put
+// against how many of the same deals can be won at all. Corpora are versioned: once
+// shipped, a version's deals and labels don't change, so results on it stay comparable.
+// A better solver or more deals means a new version.
+//
+// A corpus is one file per rule set: a Header line, then one Entry per line.
+
+//go:embed v1
+var shipped embed.FS
+
+// The newest version, used by default
+const Latest = "v1"
+
+// The labels are what the solver found. Unwinnable means it ran out of moves to try
+// before the limit, but its search is pruned (see solver.Status), so that isn't a proof:
+// a few of those deals may be winnable, like some of the Unknown.
+type Header struct {
+	Version string `json:"version"`
+	Rules string `json:"rules"`
+	FirstSeed int64 `json:"first_seed"` // The deals are sim.Deal of the seeds from here on
+	Deals int `json:"deals"`
+	MaxNodes int `json:"max_nodes"` // The solver's limit; deals it didn't settle are Unknown
+}
+
+type Entry struct {
+	Deal string `json:"deal"` // deal.Code
+	Seed int64 `json:"seed"` // Where the deal came from; it's the code that counts
+	Status solver.Status `json:"status"`
+	Length int `json:"length,omitempty"` // Of the solver's winning line, if Won
+	Nodes int `json:"nodes"`
+}
+
+func (e Entry) Deck() (deck.Deck,error) {
+	return deal.Decode(e.Deal)
+}
+
+type Corpus struct {
+	Header
+	Entries []Entry
+}
+
+// Solve the deal for `seed`
+func Label(seed int64, rules game.Rules, maxNodes int) Entry {
+	d := sim.Deal(seed)
+	r := solver.Solve(game.NewGameWithRules(d, rules), maxNodes)
+	return Entry{Deal: deal.Code(d), Seed: seed, Status: r.Status, Length: len(r.Moves), Nodes: r.Nodes}
+}
+
+// File name for a rule set, e.g. draw3_passes3.jsonl for draw3:passes=3
+func FileName(rules game.Rules) string {
+	return strings.NewReplacer(":", "_", "=", "").Replace(rules.String()) + ".jsonl"
+}
+
+// The shipped corpus for `version` and `rules`
+func Load(version string, rules game.Rules) (Corpus,error) {
+	f,err := shipped.Open(version + "/" + FileName(rules))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Corpus{},fmt.Errorf("no corpus %v for %v, there are %v", version, rules, strings.Join(Available(), ", "))
+	}
+	if err != nil {
+		return Corpus{},err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// A corpus from a file, e.g. one just built
+func ReadFile(path string) (Corpus,error) {
+	f,err := os.Open(path)
+	if err != nil {
+		return Corpus{},err
+	}
+	defer f.Close()
+	c,err := Read(f)
+	if err != nil {
+		return c,fmt.Errorf("%v: %w", path, err)
+	}
+	return c,nil
+}
+
+func Read(r io.Reader) (Corpus,error) {
+	var c Corpus
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		return c,fmt.Errorf("empty corpus")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &c.Header); err != nil {
+		return c,fmt.Errorf("header: %w", err)
+	}
+	for line := 2; scanner.Scan(); line++ {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return c,fmt.Errorf("line %v: %w", line, err)
+		}
+		c.Entries = append(c.Entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return c,err
+	}
+	if len(c.Entries) != c.Deals {
+		return c,fmt.Errorf("header says %v deals, found %v", c.Deals, len(c.Entries))
+	}
+	return c,nil
+}
+
+func (c Corpus) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(c.Header); err != nil {
+		return err
+	}
+	for _,e := range c.Entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shipped corpora, as VERSION/FILE
+func Available() []string {
+	var names []string
+	fs.WalkDir(shipped, ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			names = append(names, path)
+		}
+		return nil
+	})
+	return names
+}
+
+// Every shipped corpus, in the order of Available
+func Shipped() ([]Corpus,error) {
+	var all []Corpus
+	for _,name := range Available() {
+		f,err := shipped.Open(name)
+		if err != nil {
+			return nil,err
+		}
+		c,err := Read(f)
+		f.Close()
+		if err != nil {
+			return nil,fmt.Errorf("%v: %w", name, err)
+		}
+		all = append(all, c)
+	}
+	return all,nil
+}
+
+// Deals the solver won, searched without finding a win (see Header), and gave up on
+func (c Corpus) Counts() (won, unwinnable, unknown int) {
+	for _,e := range c.Entries {
+		switch e.Status {
+		case solver.Won:
+			won++
+		case solver.Unwinnable:
+			unwinnable++
+		default:
+			unknown++
+		}
+	}
+	return
+}
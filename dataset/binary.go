@@ -0,0 +1,193 @@
+package dataset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Compact binary shards. A shard is a .bin file of records and a .idx file holding the
+// little-endian uint64 byte offset of every record in the .bin file.
+//
+// The .bin file starts with binMagic, then each record is, little-endian:
+//
+//	int64   seed
+//	uint16  step, game length (NSteps)
+//	uint8   action, won (0 or 1)
+//	4x u8   suit stack heights
+//	7x u8   hidden cards per stack
+//	uint8   cards in the Deck
+//	uint16  passes
+//	7x      visible queue: uint8 length, then card IDs
+//	        Avail: uint8 length, then card IDs
+//	        Deck (if seen): uint8 length, then card IDs
+//	        legal actions: uint8 count, then action IDs
+var binMagic = []byte("SOLDS\x01")
+
+type binaryWriter struct {
+	bin *os.File
+	idx *os.File
+	binBuf *bufio.Writer
+	idxBuf *bufio.Writer
+	offset uint64
+	buf []byte
+}
+
+func newBinaryWriter(path string) (*binaryWriter,error) {
+	bin,err := os.Create(path + ".bin")
+	if err != nil {
+		return nil,err
+	}
+	idx,err := os.Create(path + ".idx")
+	if err != nil {
+		bin.Close()
+		return nil,err
+	}
+	w := &binaryWriter{bin: bin, idx: idx, binBuf: bufio.NewWriter(bin), idxBuf: bufio.NewWriter(idx)}
+	_,err = w.binBuf.Write(binMagic)
+	w.offset = uint64(len(binMagic))
+	return w,err
+}
+
+func appendCards(b []byte, ids []int) []byte {
+	b = append(b, byte(len(ids)))
+	for _,id := range ids {
+		b = append(b, byte(id))
+	}
+	return b
+}
+
+func (w *binaryWriter) Write(r Record) error {
+	b := w.buf[:0]
+	b = binary.LittleEndian.AppendUint64(b, uint64(r.Seed))
+	b = binary.LittleEndian.AppendUint16(b, uint16(r.Step))
+	b = binary.LittleEndian.AppendUint16(b, uint16(r.NSteps))
+	won := byte(0)
+	if r.Won {
+		won = 1
+	}
+	b = append(b, byte(r.Action), won)
+	for _,s := range r.SuitStacks {
+		b = append(b, byte(s))
+	}
+	for _,h := range r.NHidden {
+		b = append(b, byte(h))
+	}
+	b = append(b, byte(r.NDeck))
+	b = binary.LittleEndian.AppendUint16(b, uint16(r.Passes))
+	for _,q := range r.VisibleQueues {
+		b = appendCards(b, q)
+	}
+	b = appendCards(b, r.Avail)
+	b = appendCards(b, r.Deck)
+	b = appendCards(b, r.Legal)
+	w.buf = b
+
+	if err := binary.Write(w.idxBuf, binary.LittleEndian, w.offset); err != nil {
+		return err
+	}
+	_,err := w.binBuf.Write(b)
+	w.offset += uint64(len(b))
+	return err
+}
+
+func (w *binaryWriter) Close() error {
+	return errors.Join(w.binBuf.Flush(), w.idxBuf.Flush(), w.bin.Close(), w.idx.Close())
+}
+
+// Random access to a binary shard written by Generate
+type BinaryShard struct {
+	data []byte
+	offsets []uint64
+}
+
+// Open the shard with files `path`.bin and `path`.idx. Reads both into memory.
+func OpenBinary(path string) (*BinaryShard,error) {
+	data,err := os.ReadFile(path + ".bin")
+	if err != nil {
+		return nil,err
+	}
+	if len(data) < len(binMagic) || string(data[:len(binMagic)]) != string(binMagic) {
+		return nil,fmt.Errorf("%v.bin: not a dataset shard", path)
+	}
+	idx,err := os.ReadFile(path + ".idx")
+	if err != nil {
+		return nil,err
+	}
+	if len(idx) % 8 != 0 {
+		return nil,fmt.Errorf("%v.idx: truncated", path)
+	}
+	offsets := make([]uint64, len(idx) / 8)
+	for i := range offsets {
+		offsets[i] = binary.LittleEndian.Uint64(idx[8*i:])
+	}
+	return &BinaryShard{data: data, offsets: offsets}, nil
+}
+
+func (s *BinaryShard) Len() int {
+	return len(s.offsets)
+}
+
+// Record number `i` of the shard
+func (s *BinaryShard) Record(i int) (Record,error) {
+	if i < 0 || i >= len(s.offsets) {
+		return Record{},fmt.Errorf("record %v out of range [0, %v)", i, len(s.offsets))
+	}
+	d := &decoder{b: s.data[s.offsets[i]:]}
+	r := Record{
+		Seed: int64(d.u64()),
+		Step: int(d.u16()),
+		NSteps: int(d.u16()),
+		Action: int(d.u8()),
+		Won: d.u8() == 1,
+	}
+	for j := range r.SuitStacks {
+		r.SuitStacks[j] = int(d.u8())
+	}
+	for j := range r.NHidden {
+		r.NHidden[j] = int(d.u8())
+	}
+	r.NDeck = int(d.u8())
+	r.Passes = int(d.u16())
+	for j := range r.VisibleQueues {
+		r.VisibleQueues[j] = d.cards()
+	}
+	r.Avail = d.cards()
+	r.Deck = d.cards()
+	r.Legal = d.cards()
+	if d.err {
+		return Record{},io.ErrUnexpectedEOF
+	}
+	return r,nil
+}
+
+type decoder struct {
+	b []byte
+	err bool
+}
+
+func (d *decoder) take(n int) []byte {
+	if len(d.b) < n {
+		d.err = true
+		return make([]byte, n)
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *decoder) u8() byte { return d.take(1)[0] }
+func (d *decoder) u16() uint16 { return binary.LittleEndian.Uint16(d.take(2)) }
+func (d *decoder) u64() uint64 { return binary.LittleEndian.Uint64(d.take(8)) }
+
+func (d *decoder) cards() []int {
+	n := int(d.u8())
+	ids := make([]int, n)
+	for i,b := range d.take(n) {
+		ids[i] = int(b)
+	}
+	return ids
+}
@@ -0,0 +1,189 @@
+package dataset
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"solitaire/agent"
+	"solitaire/env"
+	"solitaire/game"
+	"solitaire/sim"
+)
+
+type Format string
+
+const (
+	JSONL Format = "jsonl"
+	Binary Format = "bin"
+)
+
+// Self-play dataset generation. Games are split into shards of consecutive seeds, and each
+// shard is written by a single worker in seed order, so the output only depends on the
+// seed range and strategy, not on the number of workers.
+type Config struct {
+	Strategy agent.Strategy
+	StrategyName string // For the manifest: Strategy is the registry's StrategyName with Params
+	Params map[string]string // All of them, defaults included
+	Rules game.Rules // DefaultRules if zero
+	FirstSeed int64
+	NGames int
+	GamesPerShard int
+	Workers int
+	Format Format
+	OutDir string
+}
+
+// Written to OutDir/manifest.json, describing how to regenerate the dataset
+type Manifest struct {
+	Strategy string `json:"strategy"` // Registry name
+	Params map[string]string `json:"params"`
+	Rules string `json:"rules"`
+	FirstSeed int64 `json:"first_seed"`
+	NGames int `json:"n_games"`
+	GamesPerShard int `json:"games_per_shard"`
+	Format Format `json:"format"`
+	EncodingVersion int `json:"encoding_version"`
+	Shards []Shard `json:"shards"`
+}
+
+type Shard struct {
+	Path string `json:"path"` // Relative to OutDir, without extension for binary shards
+	FirstSeed int64 `json:"first_seed"`
+	NGames int `json:"n_games"`
+	NRecords int `json:"n_records"`
+	NWins int `json:"n_wins"`
+}
+
+type recordWriter interface {
+	Write(Record) error
+	Close() error
+}
+
+type jsonlWriter struct {
+	f *os.File
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newJSONLWriter(path string) (*jsonlWriter,error) {
+	f,err := os.Create(path + ".jsonl")
+	if err != nil {
+		return nil,err
+	}
+	buf := bufio.NewWriter(f)
+	return &jsonlWriter{f: f, buf: buf, enc: json.NewEncoder(buf)}, nil
+}
+
+func (w *jsonlWriter) Write(r Record) error {
+	return w.enc.Encode(r)
+}
+
+func (w *jsonlWriter) Close() error {
+	if err := w.buf.Flush(); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+func Generate(cfg Config) (Manifest,error) {
+	if cfg.Format != JSONL && cfg.Format != Binary {
+		return Manifest{},fmt.Errorf("unknown dataset format %q", cfg.Format)
+	}
+	if cfg.GamesPerShard <= 0 {
+		return Manifest{},fmt.Errorf("games per shard must be positive, got %v", cfg.GamesPerShard)
+	}
+	if err := os.MkdirAll(cfg.OutDir, 0o755); err != nil {
+		return Manifest{},err
+	}
+
+	if cfg.Rules == (game.Rules{}) {
+		cfg.Rules = game.DefaultRules
+	}
+	manifest := Manifest{
+		Strategy: cfg.StrategyName,
+		Params: cfg.Params,
+		Rules: cfg.Rules.String(),
+		FirstSeed: cfg.FirstSeed,
+		NGames: cfg.NGames,
+		GamesPerShard: cfg.GamesPerShard,
+		Format: cfg.Format,
+		EncodingVersion: env.EncodingVersion,
+	}
+	for first := 0; first < cfg.NGames; first += cfg.GamesPerShard {
+		manifest.Shards = append(manifest.Shards, Shard{
+			Path: fmt.Sprintf("shard-%05d", len(manifest.Shards)),
+			FirstSeed: cfg.FirstSeed + int64(first),
+			NGames: min(cfg.GamesPerShard, cfg.NGames - first),
+		})
+	}
+
+	jobs := make(chan int)
+	errs := make([]error, len(manifest.Shards))
+	var wg sync.WaitGroup
+	for range max(cfg.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = cfg.writeShard(&manifest.Shards[i])
+			}
+		}()
+	}
+	for i := range manifest.Shards {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	for _,err := range errs {
+		if err != nil {
+			return manifest,err
+		}
+	}
+
+	data,err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest,err
+	}
+	return manifest,os.WriteFile(filepath.Join(cfg.OutDir, "manifest.json"), data, 0o644)
+}
+
+func (cfg Config) writeShard(shard *Shard) error {
+	path := filepath.Join(cfg.OutDir, shard.Path)
+	var w recordWriter
+	var err error
+	if cfg.Format == Binary {
+		w,err = newBinaryWriter(path)
+	} else {
+		w,err = newJSONLWriter(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	for seed := shard.FirstSeed; seed < shard.FirstSeed + int64(shard.NGames); seed++ {
+		// The outcome is only known at the end, so hold on to the game's records until then
+		var records []Record
+		observe := func(g *game.Game, moves *agent.Moves, moveID int) {
+			records = append(records, newRecord(seed, len(records), g, moves, moveID))
+		}
+		won := sim.Play(cfg.Strategy, seed, sim.Options{Rules: cfg.Rules, Observe: observe}).Won
+		if won {
+			shard.NWins++
+		}
+		for _,r := range records {
+			r.Won = won
+			r.NSteps = len(records)
+			if err := w.Write(r); err != nil {
+				w.Close()
+				return err
+			}
+		}
+		shard.NRecords += len(records)
+	}
+	return w.Close()
+}
@@ -0,0 +1,87 @@
+package dataset
+
+import (
+	"solitaire/agent"
+	"solitaire/deck"
+	"solitaire/env"
+	"solitaire/game"
+)
+
+// One decision in a self-play game: what the player saw, what it could do, what it did,
+// and how the game ended. Cards are numbered 13*suit + rank and moves are env action IDs,
+// the same numbering as env.Encode and env.ActionID.
+type Record struct {
+	Seed int64 `json:"seed"`
+	Step int `json:"step"`
+	SuitStacks [deck.NSuits]int `json:"suit_stacks"`
+	VisibleQueues [game.NStacks][]int `json:"visible_queues"`
+	NHidden [game.NStacks]int `json:"n_hidden"`
+	Avail []int `json:"avail"`
+	Deck []int `json:"deck"` // Empty until the Deck has been seen
+	NDeck int `json:"n_deck"`
+	Passes int `json:"passes"`
+	Legal []int `json:"legal"`
+	Action int `json:"action"`
+	Won bool `json:"won"` // Outcome of the whole game
+	NSteps int `json:"n_steps"` // Length of the whole game
+}
+
+func cardIDs(cards []deck.Card) []int {
+	ids := make([]int, len(cards))
+	for i,c := range cards {
+		ids[i] = c.Index()
+	}
+	return ids
+}
+
+func idCards(ids []int) []deck.Card {
+	if ids == nil {
+		return nil
+	}
+	cards := make([]deck.Card, len(ids))
+	for i,id := range ids {
+		cards[i] = deck.CardAt(id)
+	}
+	return cards
+}
+
+func newRecord(seed int64, step int, g *game.Game, moves *agent.Moves, moveID int) Record {
+	view := g.View()
+	r := Record{
+		Seed: seed,
+		Step: step,
+		SuitStacks: view.SuitStacks,
+		NHidden: view.NHidden,
+		Avail: cardIDs(view.Avail),
+		Deck: cardIDs(view.Deck),
+		NDeck: view.NDeck,
+		Passes: view.Passes,
+		Legal: []int{env.ActionID(moves.At(-1))},
+		Action: env.ActionID(moves.At(moveID)),
+	}
+	for i,q := range view.VisibleQueues {
+		r.VisibleQueues[i] = cardIDs(q)
+	}
+	for i := range moves.Len() {
+		r.Legal = append(r.Legal, env.ActionID(moves.At(i)))
+	}
+	return r
+}
+
+// The view the record was made from, e.g. to pass to env.Encode
+func (r Record) View() game.View {
+	view := game.View{
+		SuitStacks: r.SuitStacks,
+		NHidden: r.NHidden,
+		Avail: idCards(r.Avail),
+		NDeck: r.NDeck,
+		Passes: r.Passes,
+	}
+	if r.Passes > 0 {
+		view.Deck = idCards(r.Deck)
+	}
+	for i,q := range r.VisibleQueues {
+		view.VisibleQueues[i] = idCards(q)
+	}
+	return view
+}
@@ -0,0 +1,102 @@
+package deal
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"solitaire/deck"
+)
+
+// A batch of deals on disk: the header, then each deal as its code in CodeBytes bytes.
+// At 29 bytes a deal, a million deals are 29MB.
+var batchMagic = []byte("SOLDEALS")
+
+const batchVersion = 1
+
+type BatchWriter struct {
+	w *bufio.Writer
+	buf []byte
+	started bool
+}
+
+func NewBatchWriter(w io.Writer) *BatchWriter {
+	return &BatchWriter{w: bufio.NewWriter(w)}
+}
+
+func (bw *BatchWriter) Write(d deck.Deck) error {
+	bw.buf = bw.buf[:0]
+	if !bw.started {
+		bw.buf = append(append(bw.buf, batchMagic...), batchVersion)
+		bw.started = true
+	}
+	bw.buf = AppendBinary(bw.buf, d)
+	_,err := bw.w.Write(bw.buf)
+	return err
+}
+
+// Writes the header even for an empty batch
+func (bw *BatchWriter) Flush() error {
+	if !bw.started {
+		if _,err := bw.w.Write(append(batchMagic, batchVersion)); err != nil {
+			return err
+		}
+		bw.started = true
+	}
+	return bw.w.Flush()
+}
+
+type BatchReader struct {
+	r *bufio.Reader
+	buf [CodeBytes]byte
+	started bool
+	n int
+}
+
+func NewBatchReader(r io.Reader) *BatchReader {
+	return &BatchReader{r: bufio.NewReader(r)}
+}
+
+// The next deal, or io.EOF after the last
+func (br *BatchReader) Read() (deck.Deck,error) {
+	if !br.started {
+		header := make([]byte, len(batchMagic) + 1)
+		if _,err := io.ReadFull(br.r, header); err != nil || !bytes.Equal(header[:len(batchMagic)], batchMagic) {
+			return deck.Deck{},fmt.Errorf("not a batch of deals")
+		}
+		if header[len(batchMagic)] != batchVersion {
+			return deck.Deck{},fmt.Errorf("batch of deals has version %v, expected %v", header[len(batchMagic)], batchVersion)
+		}
+		br.started = true
+	}
+	if _,err := io.ReadFull(br.r, br.buf[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return deck.Deck{},fmt.Errorf("deal %v of the batch is cut short", br.n)
+		}
+		return deck.Deck{},err
+	}
+	d,err := FromBinary(br.buf[:])
+	if err != nil {
+		return d,fmt.Errorf("deal %v of the batch: %w", br.n, err)
+	}
+	br.n++
+	return d,nil
+}
+
+// Every deal in a batch
+func ReadBatch(r io.Reader) ([]deck.Deck,error) {
+	br := NewBatchReader(r)
+	var deals []deck.Deck
+	for {
+		d,err := br.Read()
+		if err == io.EOF {
+			return deals,nil
+		}
+		if err != nil {
+			return nil,err
+		}
+		deals = append(deals, d)
+	}
+}
@@ -0,0 +1,132 @@
+package deal
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"solitaire/deck"
+)
+
+// Deals as codes that don't depend on a random number generator: a deal is an ordering
+// of the 52 cards, and the orderings can be numbered from 0 to 52!-1 (the Lehmer code,
+// 226 bits). Printed in base 64, that's a code of CodeLen characters.
+
+const nCards = len(deck.Deck{})
+
+// Bytes in the binary form of a code, big endian
+const CodeBytes = 29
+
+// Characters in a printed code: 64^38 > 52!
+const CodeLen = 38
+
+const codeDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_"
+
+var nDeals = func() *big.Int {
+	n := big.NewInt(1)
+	for i := 2; i <= nCards; i++ {
+		n.Mul(n, big.NewInt(int64(i)))
+	}
+	return n
+}()
+
+// The number of the ordering `d`, from 0 to 52!-1. An error if `d` isn't every card once.
+func Rank(d deck.Deck) (*big.Int,error) {
+	// Each card's digit is how many of the cards not yet placed come before it in a new
+	// deck, so the i'th digit is less than 52-i
+	var used [nCards]bool
+	r := new(big.Int)
+	radix, digit := new(big.Int), new(big.Int)
+	for i,c := range d {
+		idx := c.Index()
+		if idx < 0 || idx >= nCards || used[idx] {
+			return nil,fmt.Errorf("not a deck: %v at position %v is invalid or repeated", c, i)
+		}
+		used[idx] = true
+		n := 0
+		for j := range idx {
+			if !used[j] {
+				n++
+			}
+		}
+		r.Mul(r, radix.SetInt64(int64(nCards - i)))
+		r.Add(r, digit.SetInt64(int64(n)))
+	}
+	return r,nil
+}
+
+// The deck with number `r`, see Rank
+func Unrank(r *big.Int) (deck.Deck,error) {
+	var d deck.Deck
+	if r.Sign() < 0 || r.Cmp(nDeals) >= 0 {
+		return d,fmt.Errorf("deal number out of range")
+	}
+	var digits [nCards]int
+	q := new(big.Int).Set(r)
+	radix, rem := new(big.Int), new(big.Int)
+	for i := nCards - 1; i >= 0; i-- {
+		q.QuoRem(q, radix.SetInt64(int64(nCards - i)), rem)
+		digits[i] = int(rem.Int64())
+	}
+	remaining := deck.NewDeck()
+	left := remaining[:]
+	for i,n := range digits {
+		d[i] = left[n]
+		left = append(left[:n], left[n+1:]...)
+	}
+	return d,nil
+}
+
+// The printed code for `d`, which has to be a full deck
+func Code(d deck.Deck) string {
+	r,err := Rank(d)
+	if err != nil {
+		panic(err)
+	}
+	var code [CodeLen]byte
+	base, rem := big.NewInt(int64(len(codeDigits))), new(big.Int)
+	for i := CodeLen - 1; i >= 0; i-- {
+		r.QuoRem(r, base, rem)
+		code[i] = codeDigits[rem.Int64()]
+	}
+	return string(code[:])
+}
+
+// The deck for a code from Code
+func Decode(code string) (deck.Deck,error) {
+	if len(code) != CodeLen {
+		return deck.Deck{},fmt.Errorf("bad deal code %q: expected %v characters, got %v", code, CodeLen, len(code))
+	}
+	r := new(big.Int)
+	base, digit := big.NewInt(int64(len(codeDigits))), new(big.Int)
+	for _,ch := range []byte(code) {
+		n := strings.IndexByte(codeDigits, ch)
+		if n < 0 {
+			return deck.Deck{},fmt.Errorf("bad deal code %q: %q isn't a digit", code, ch)
+		}
+		r.Mul(r, base)
+		r.Add(r, digit.SetInt64(int64(n)))
+	}
+	d,err := Unrank(r)
+	if err != nil {
+		return d,fmt.Errorf("bad deal code %q: %w", code, err)
+	}
+	return d,nil
+}
+
+// The binary form of the code for `d`, for batches
+func AppendBinary(b []byte, d deck.Deck) []byte {
+	r,err := Rank(d)
+	if err != nil {
+		panic(err)
+	}
+	var buf [CodeBytes]byte
+	return append(b, r.FillBytes(buf[:])...)
+}
+
+func FromBinary(b []byte) (deck.Deck,error) {
+	if len(b) != CodeBytes {
+		return deck.Deck{},fmt.Errorf("expected %v bytes, got %v", CodeBytes, len(b))
+	}
+	return Unrank(new(big.Int).SetBytes(b))
+}
@@ -0,0 +1,191 @@
+package deal
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+
+	"solitaire/deck"
+	"solitaire/sim"
+)
+
+func testDeals() []deck.Deck {
+	deals := []deck.Deck{deck.NewDeck()}
+	for seed := range int64(50) {
+		deals = append(deals, sim.Deal(seed))
+	}
+	return deals
+}
+
+func TestRankRoundTrip(t *testing.T) {
+	for _,d := range testDeals() {
+		r,err := Rank(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		back,err := Unrank(r)
+		if err != nil {
+			t.Fatalf("%v: %v", r, err)
+		}
+		if back != d {
+			t.Errorf("%v: unranks to a different deck", r)
+		}
+	}
+	// The ends of the range
+	if r,_ := Rank(deck.NewDeck()); r.Sign() != 0 {
+		t.Errorf("a new deck ranks %v, not 0", r)
+	}
+	last,err := Unrank(new(big.Int).Sub(nDeals, big.NewInt(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r,_ := Rank(last); r.Cmp(new(big.Int).Sub(nDeals, big.NewInt(1))) != 0 {
+		t.Errorf("the last deal ranks %v", r)
+	}
+}
+
+func TestRankRejectsRepeats(t *testing.T) {
+	d := deck.NewDeck()
+	d[1] = d[0]
+	if _,err := Rank(d); err == nil {
+		t.Error("a deck with a card twice ranks")
+	}
+}
+
+func TestUnrankRejectsOutOfRange(t *testing.T) {
+	for _,r := range []*big.Int{big.NewInt(-1), nDeals, new(big.Int).Add(nDeals, big.NewInt(1))} {
+		if _,err := Unrank(r); err == nil {
+			t.Errorf("%v: no error", r)
+		}
+	}
+}
+
+func TestCodeRoundTrip(t *testing.T) {
+	for _,d := range testDeals() {
+		code := Code(d)
+		if len(code) != CodeLen {
+			t.Errorf("%q: %v characters, not %v", code, len(code), CodeLen)
+		}
+		back,err := Decode(code)
+		if err != nil {
+			t.Fatalf("%q: %v", code, err)
+		}
+		if back != d {
+			t.Errorf("%q: decodes to a different deck", code)
+		}
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	good := Code(sim.Deal(1))
+	// 64^38 > 52!, so the largest code is out of range
+	tooBig := strings.Repeat(codeDigits[len(codeDigits)-1:], CodeLen)
+	for _,code := range []string{
+		"",
+		good[1:],
+		good + "0",
+		good[:10] + "!" + good[11:],
+		good[:10] + " " + good[11:],
+		tooBig,
+	} {
+		if _,err := Decode(code); err == nil {
+			t.Errorf("%q: no error", code)
+		}
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	for _,d := range testDeals() {
+		b := AppendBinary([]byte{1, 2}, d)
+		if len(b) != 2 + CodeBytes {
+			t.Fatalf("%v bytes, not %v", len(b) - 2, CodeBytes)
+		}
+		back,err := FromBinary(b[2:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if back != d {
+			t.Errorf("%x: reads back as a different deck", b[2:])
+		}
+	}
+}
+
+func TestFromBinaryRejects(t *testing.T) {
+	b := AppendBinary(nil, sim.Deal(1))
+	if _,err := FromBinary(b[1:]); err == nil {
+		t.Error("too short: no error")
+	}
+	if _,err := FromBinary(append(b, 0)); err == nil {
+		t.Error("too long: no error")
+	}
+	var tooBig [CodeBytes]byte
+	nDeals.FillBytes(tooBig[:])
+	if _,err := FromBinary(tooBig[:]); err == nil {
+		t.Error("52!: no error")
+	}
+}
+
+func TestBatchRoundTrip(t *testing.T) {
+	for _,deals := range [][]deck.Deck{nil, testDeals()} {
+		var buf bytes.Buffer
+		bw := NewBatchWriter(&buf)
+		for _,d := range deals {
+			if err := bw.Write(d); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if want := len(batchMagic) + 1 + len(deals)*CodeBytes; buf.Len() != want {
+			t.Errorf("%v deals: %v bytes, not %v", len(deals), buf.Len(), want)
+		}
+		back,err := ReadBatch(&buf)
+		if err != nil {
+			t.Fatalf("%v deals: %v", len(deals), err)
+		}
+		if len(back) != len(deals) {
+			t.Fatalf("%v deals read back as %v", len(deals), len(back))
+		}
+		for i := range deals {
+			if back[i] != deals[i] {
+				t.Errorf("deal %v reads back as a different deck", i)
+			}
+		}
+	}
+}
+
+func TestReadBatchRejects(t *testing.T) {
+	var buf bytes.Buffer
+	bw := NewBatchWriter(&buf)
+	bw.Write(sim.Deal(1))
+	bw.Flush()
+	good := buf.Bytes()
+
+	badVersion := bytes.Clone(good)
+	badVersion[len(batchMagic)]++
+	for name,b := range map[string][]byte{
+		"empty": nil,
+		"not a batch": []byte("NOTDEALS\x01"),
+		"other version": badVersion,
+		"cut short": good[:len(good)-1],
+	} {
+		if _,err := ReadBatch(bytes.NewReader(b)); err == nil {
+			t.Errorf("%v: no error", name)
+		}
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int,error) {
+	return 0,io.ErrShortWrite
+}
+
+func TestFlushReportsErrors(t *testing.T) {
+	if err := NewBatchWriter(failWriter{}).Flush(); err == nil {
+		t.Error("an empty batch that can't be written: no error")
+	}
+}
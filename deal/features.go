@@ -0,0 +1,113 @@
+package deal
+
+import (
+	"solitaire/agent"
+	"solitaire/deck"
+	"solitaire/game"
+)
+
+// What can be read off a deal before playing it, for guessing how hard it is
+type Features struct {
+	// Cards on top of each ace and two in the tableau, by suit; -1 if it's in the stock
+	AceDepth [deck.NSuits]int `json:"ace_depth"`
+	TwoDepth [deck.NSuits]int `json:"two_depth"`
+
+	AcesCovered int `json:"aces_covered"` // Totals of the above, for aces and twos in the tableau
+	TwosCovered int `json:"twos_covered"`
+	AcesInStock int `json:"aces_in_stock"`
+	// A card over a lower card of the same colour in the same column. Neither can go on
+	// the other, so the top one has to go somewhere else before the lower one is free.
+	BlockingPairs int `json:"blocking_pairs"`
+	KingsBuried int `json:"kings_buried"` // In the tableau, with cards under them
+	// Stock cards that come up on the first pass (as agent.AvailCards) and can be played
+	// straight onto the layout as dealt, or are aces and twos
+	StockPlayable int `json:"stock_playable"`
+	StockLow int `json:"stock_low"` // Of those, aces and twos
+	OpeningMoves int `json:"opening_moves"` // Moves other than flipping at the start
+}
+
+// Features in a fixed order, for the difficulty model
+var FeatureNames = []string{"aces_covered", "twos_covered", "aces_in_stock", "blocking_pairs",
+	"kings_buried", "stock_playable", "stock_low", "opening_moves"}
+
+func (f Features) vector() []float64 {
+	return []float64{float64(f.AcesCovered), float64(f.TwosCovered), float64(f.AcesInStock), float64(f.BlockingPairs),
+		float64(f.KingsBuried), float64(f.StockPlayable), float64(f.StockLow), float64(f.OpeningMoves)}
+}
+
+func Analyze(d deck.Deck, rules game.Rules) Features {
+	g := game.NewGameWithRules(d, rules)
+	var f Features
+	for suit := range deck.NSuits {
+		f.AceDepth[suit] = covering(g, deck.NewCard(int(deck.Ace), suit))
+		f.TwoDepth[suit] = covering(g, deck.NewCard(int(deck.Two), suit))
+		if f.AceDepth[suit] < 0 {
+			f.AcesInStock++
+		} else {
+			f.AcesCovered += f.AceDepth[suit]
+		}
+		f.TwosCovered += max(f.TwoDepth[suit], 0)
+	}
+
+	for col := range game.NStacks {
+		// Top of the column first
+		column := append([]deck.Card{g.VisibleQueues[col][0]}, reversed(g.HiddenStacks[col])...)
+		for i,c := range column {
+			for _,under := range column[i+1:] {
+				if under.Color() == c.Color() && under.Rank < c.Rank {
+					f.BlockingPairs++
+				}
+			}
+			if c.Rank == deck.King && i < len(column) - 1 {
+				f.KingsBuried++
+			}
+		}
+	}
+
+	stock,nFirst := agent.AvailCards(g)
+	for _,c := range stock[:nFirst] {
+		low := c.Rank <= deck.Two
+		if low {
+			f.StockLow++
+		}
+		if low || fitsLayout(g, c) {
+			f.StockPlayable++
+		}
+	}
+	moves := agent.FindMoves(g)
+	f.OpeningMoves = moves.Len()
+	return f
+}
+
+// Cards on top of `c` in the tableau, or -1 if it's not there
+func covering(g *game.Game, c deck.Card) int {
+	for col := range game.NStacks {
+		if g.VisibleQueues[col][0] == c {
+			return 0
+		}
+		hidden := g.HiddenStacks[col]
+		for i,h := range hidden {
+			if h == c {
+				return len(hidden) - i
+			}
+		}
+	}
+	return -1
+}
+
+func fitsLayout(g *game.Game, c deck.Card) bool {
+	for col := range game.NStacks {
+		if deck.CanPlace(c, g.VisibleQueues[col][0]) {
+			return true
+		}
+	}
+	return false
+}
+
+func reversed(cards []deck.Card) []deck.Card {
+	r := make([]deck.Card, len(cards))
+	for i,c := range cards {
+		r[len(cards)-1-i] = c
+	}
+	return r
+}
@@ -0,0 +1,210 @@
+package deal
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+
+	"solitaire/solver"
+)
+
+// A logistic model of a strategy's chance of winning a deal from its Features, fitted
+// on deals it played. Difficulty is then how a deal ranks among the deals it was fitted
+// on, so it reads the same whatever the strategy's overall win rate.
+type Model struct {
+	Strategy string `json:"strategy"` // What it was fitted on
+	Rules string `json:"rules"`
+	Games int `json:"games"`
+	Features []string `json:"features"` // FeatureNames when it was fitted
+	Mean []float64 `json:"mean"` // Features are standardised before weighting
+	Scale []float64 `json:"scale"`
+	Weights []float64 `json:"weights"`
+	Bias float64 `json:"bias"`
+	// Win chances at the 0th, 1st, ..., 100th percentiles of the deals fitted on
+	Percentiles []float64 `json:"percentiles"`
+}
+
+func (m Model) logit(f Features) float64 {
+	z := m.Bias
+	for i,x := range f.vector() {
+		z += m.Weights[i] * (x - m.Mean[i]) / m.Scale[i]
+	}
+	return z
+}
+
+// The strategy's chance of winning the deal
+func (m Model) WinChance(f Features) float64 {
+	return 1 / (1 + math.Exp(-m.logit(f)))
+}
+
+// From 0 for the easiest deals to 1 for the hardest: the fraction of the deals fitted on
+// that the strategy had a better chance at
+func (m Model) Difficulty(f Features) float64 {
+	p := m.WinChance(f)
+	// Percentiles go up with the win chance, difficulty goes down
+	// A better chance than any of them still only gets the easiest percentile
+	i := min(sort.SearchFloat64s(m.Percentiles, p), len(m.Percentiles) - 1)
+	return 1 - float64(i) / float64(len(m.Percentiles) - 1)
+}
+
+// Fit by Newton's method (it's a small problem), with a little L2 regularisation so
+// that features which never vary don't blow up
+func Fit(features []Features, won []bool) Model {
+	n, k := len(features), len(FeatureNames)
+	m := Model{Features: FeatureNames, Games: n, Mean: make([]float64, k), Scale: make([]float64, k), Weights: make([]float64, k)}
+	xs := make([][]float64, n)
+	for i,f := range features {
+		xs[i] = f.vector()
+		for j,x := range xs[i] {
+			m.Mean[j] += x / float64(n)
+		}
+	}
+	for _,x := range xs {
+		for j := range x {
+			m.Scale[j] += (x[j] - m.Mean[j]) * (x[j] - m.Mean[j]) / float64(n)
+		}
+	}
+	for j := range m.Scale {
+		m.Scale[j] = math.Sqrt(m.Scale[j])
+		if m.Scale[j] == 0 {
+			m.Scale[j] = 1
+		}
+	}
+	// Standardised, with a 1 on the end for the bias
+	for i,x := range xs {
+		row := make([]float64, k+1)
+		for j := range x {
+			row[j] = (x[j] - m.Mean[j]) / m.Scale[j]
+		}
+		row[k] = 1
+		xs[i] = row
+	}
+
+	const l2 = 1e-3
+	w := make([]float64, k+1)
+	for range 25 {
+		grad := make([]float64, k+1)
+		hess := make([][]float64, k+1)
+		for j := range hess {
+			hess[j] = make([]float64, k+1)
+		}
+		for i,x := range xs {
+			z := 0.0
+			for j := range x {
+				z += w[j] * x[j]
+			}
+			p := 1 / (1 + math.Exp(-z))
+			y := 0.0
+			if won[i] {
+				y = 1
+			}
+			for a := range x {
+				grad[a] += (p - y) * x[a]
+				for b := range x {
+					hess[a][b] += p * (1 - p) * x[a] * x[b]
+				}
+			}
+		}
+		for a := range k {
+			grad[a] += l2 * float64(n) * w[a]
+			hess[a][a] += l2 * float64(n)
+		}
+		step := solve(hess, grad)
+		for j := range w {
+			w[j] -= step[j]
+		}
+	}
+	copy(m.Weights, w[:k])
+	m.Bias = w[k]
+
+	chances := make([]float64, n)
+	for i,f := range features {
+		chances[i] = m.WinChance(f)
+	}
+	slices.Sort(chances)
+	for q := 0; q <= 100; q++ {
+		m.Percentiles = append(m.Percentiles, chances[min(q * n / 100, n-1)])
+	}
+	return m
+}
+
+// Solve a x = b by Gaussian elimination with partial pivoting
+func solve(a [][]float64, b []float64) []float64 {
+	n := len(b)
+	m := make([][]float64, n)
+	for i := range a {
+		m[i] = append(append([]float64(nil), a[i]...), b[i])
+	}
+	for col := range n {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(m[r][col]) > math.Abs(m[pivot][col]) {
+				pivot = r
+			}
+		}
+		m[col],m[pivot] = m[pivot],m[col]
+		for r := col + 1; r < n; r++ {
+			f := m[r][col] / m[col][col]
+			for c := col; c <= n; c++ {
+				m[r][c] -= f * m[col][c]
+			}
+		}
+	}
+	x := make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		s := m[r][n]
+		for c := r + 1; c < n; c++ {
+			s -= m[r][c] * x[c]
+		}
+		x[r] = s / m[r][r]
+	}
+	return x
+}
+
+func LoadModel(path string) (Model,error) {
+	var m Model
+	data,err := os.ReadFile(path)
+	if err != nil {
+		return m,err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m,fmt.Errorf("%v: %w", path, err)
+	}
+	if !slices.Equal(m.Features, FeatureNames) {
+		return m,fmt.Errorf("%v: fitted on features %v, but deals now have %v", path, m.Features, FeatureNames)
+	}
+	return m,nil
+}
+
+func (m Model) Save(path string) error {
+	data,err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+//go:embed default_model.json
+var defaultModel []byte
+
+// Fitted with `solitaire calibrate` on 16000 deals played by StockStrategy, draw 3
+var Default = func() Model {
+	var m Model
+	if err := json.Unmarshal(defaultModel, &m); err != nil {
+		panic(err)
+	}
+	return m
+}()
+
+// Difficulty, taking the solver's verdict into account if the deal was solved: one that
+// can't be won is as hard as it gets, whatever its features say
+func (m Model) Rate(f Features, status solver.Status) float64 {
+	if status == solver.Unwinnable {
+		return 1
+	}
+	return m.Difficulty(f)
+}
@@ -0,0 +1,141 @@
+package deal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+
+	"solitaire/deck"
+	"solitaire/game"
+	"solitaire/sim"
+	"solitaire/solver"
+)
+
+// A deal that can be won, with the winning line to prove it
+type Certified struct {
+	Deal string `json:"deal"` // Code
+	Seed int64 `json:"seed"` // Where the deal came from; it's the code that counts
+	Rules string `json:"rules"`
+	Difficulty float64 `json:"difficulty"`
+	Moves []solver.Move `json:"moves"` // The certificate
+}
+
+// Difficulties from Min up to but not including Max, except that a Max of 1 includes 1
+type Target struct {
+	Min float64
+	Max float64
+}
+
+var Targets = map[string]Target{
+	"any": {0, 1},
+	"easy": {0, 1./3},
+	"medium": {1./3, 2./3},
+	"hard": {2./3, 1},
+}
+
+// A name from Targets, or a range like 0.2-0.5
+func ParseTarget(s string) (Target,error) {
+	if t,ok := Targets[s]; ok {
+		return t,nil
+	}
+	lo,hi,ok := strings.Cut(s, "-")
+	min,err1 := strconv.ParseFloat(lo, 64)
+	max,err2 := strconv.ParseFloat(hi, 64)
+	if !ok || err1 != nil || err2 != nil || min < 0 || max > 1 || min >= max {
+		return Target{},fmt.Errorf("bad difficulty %q, expected any, easy, medium, hard or a range like 0.2-0.5", s)
+	}
+	return Target{min, max},nil
+}
+
+func (t Target) Contains(d float64) bool {
+	return d >= t.Min && (d < t.Max || t.Max == 1)
+}
+
+// Deals from random seeds, until one in the target range is won by the solver. Deals the
+// solver can't settle within maxNodes positions are skipped, which favours deals with
+// short proofs but keeps each one quick. Gives up after `tries` deals.
+func FindWinnable(rng *rand.Rand, rules game.Rules, model Model, target Target, maxNodes, tries int) (Certified,error) {
+	for range tries {
+		seed := rng.Int63()
+		d := sim.Deal(seed)
+		difficulty := model.Difficulty(Analyze(d, rules))
+		if !target.Contains(difficulty) {
+			continue
+		}
+		r := solver.Solve(game.NewGameWithRules(d, rules), maxNodes)
+		if r.Status == solver.Won {
+			return Certified{Deal: Code(d), Seed: seed, Rules: rules.String(), Difficulty: difficulty, Moves: r.Moves},nil
+		}
+	}
+	return Certified{},fmt.Errorf("no winnable deal with difficulty %v-%v in %v tries", target.Min, target.Max, tries)
+}
+
+func (c Certified) Deck() (deck.Deck,error) {
+	return Decode(c.Deal)
+}
+
+// Check the certificate: replay it on the deal's code and see that it wins
+func (c Certified) Verify() error {
+	rules,err := game.ParseRules(c.Rules)
+	if err != nil {
+		return err
+	}
+	d,err := c.Deck()
+	if err != nil {
+		return fmt.Errorf("deal %v: %w", c.Seed, err)
+	}
+	g := game.NewGameWithRules(d, rules)
+	for i,m := range c.Moves {
+		if err := solver.Apply(g, m); err != nil {
+			return fmt.Errorf("deal %v: move %v (%v) of the certificate is illegal: %w", c.Deal, i+1, m, err)
+		}
+	}
+	if !g.IsWon() {
+		return fmt.Errorf("deal %v: the certificate doesn't win", c.Deal)
+	}
+	return nil
+}
+
+// Certified deals, one JSON object per line
+func ReadCertified(path string) ([]Certified,error) {
+	f,err := os.Open(path)
+	if err != nil {
+		return nil,err
+	}
+	defer f.Close()
+	var deals []Certified
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1 << 20)
+	for line := 1; scanner.Scan(); line++ {
+		var c Certified
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			return nil,fmt.Errorf("%v:%v: %w", path, line, err)
+		}
+		deals = append(deals, c)
+	}
+	return deals,scanner.Err()
+}
+
+func AppendCertified(path string, deals []Certified) error {
+	f,err := os.OpenFile(path, os.O_WRONLY | os.O_CREATE | os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _,c := range deals {
+		if err := enc.Encode(c); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -25,6 +25,16 @@ func (c Card) Color() byte {
 	return byte(c.Suit) % 2
 }
 
+// Position in NewDeck, from 0 to 51. Used wherever a card needs a number.
+func (c Card) Index() int {
+	return int(c.Suit)*SuitSize + int(c.Rank)
+}
+
+// The card at position `i` in NewDeck, the inverse of Index
+func CardAt(i int) Card {
+	return NewCard(i % SuitSize, i / SuitSize)
+}
+
 const (
 	Spades SuitT = iota
 	Hearts
@@ -115,6 +125,13 @@ func (d *Deck) Shuffle() {
 	})
 }
 
+// Shuffle using `r` instead of the global source, so a deal can be reproduced from its seed
+func (d *Deck) ShuffleWith(r *rand.Rand) {
+	r.Shuffle(len(d), func(i, j int) {
+		d[i], d[j] = d[j], d[i]
+	})
+}
+
 func CanPlace(card, dest Card) bool {
 	return dest.Rank == card.Rank + 1 && card.Color() != dest.Color()
 }
\ No newline at end of file
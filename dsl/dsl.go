@@ -0,0 +1,156 @@
+// A small rule language for writing strategies without writing Go. A rules file has one
+// rule per line, with # for comments:
+//
+//	# Aces and twos can always go up
+//	when move.kind == ToTop and card.rank <= 2 then priority 100
+//	when move.reveals then priority 50 + src.hidden
+//	when move.kind == AvailToTableau then weight 2
+//	when move.kind == Flip then weight 1
+//
+// Every rule is checked against every legal move (Flip included). A move's priority is the
+// highest priority of the rules it matches, and its weight the sum of the weights of the
+// rules it matches. The moves with the highest priority are the candidates, or if no move
+// matched a priority rule, every move with a weight. Of the candidates, one with a weight
+// is picked at random in proportion to weight, or if none has any, the first one, with
+// Flip last. If there are no candidates at all, the strategy abstains (see agent.Chain).
+//
+// Conditions are expressions over numbers, bools, move kinds and suits, with the operators
+// ==, !=, <, <=, >, >=, +, -, and, or, not and parentheses. Priorities and weights are
+// number expressions, worked out for each move; negative weights count as 0. See Names for
+// what an expression can look at.
+package dsl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+
+	"solitaire/agent"
+	"solitaire/game"
+)
+
+type Program struct {
+	rules []rule
+}
+
+// Parse rules from `src`. `file` is only used in error messages. All errors are returned,
+// joined, each with its line and column.
+func Parse(file string, src string) (*Program,error) {
+	prog := &Program{}
+	var errs []error
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		tokens,err := lex(file, lineNo, scanner.Text())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if tokens[0].kind == tokEOL {
+			continue // Blank or comment
+		}
+		p := parser{file: file, line: lineNo, tokens: tokens}
+		r,err := p.rule()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		prog.rules = append(prog.rules, r)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil,errors.Join(errs...)
+	}
+	return prog,nil
+}
+
+func Load(path string) (*Program,error) {
+	data,err := os.ReadFile(path)
+	if err != nil {
+		return nil,err
+	}
+	return Parse(path, string(data))
+}
+
+// What rules can refer to, with a description of each, for help text
+func Names() []string {
+	var names []string
+	for name,v := range variables {
+		names = append(names, fmt.Sprintf("%-20v %v (%v)", name, v.doc, v.typ))
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (prog *Program) Strategy() agent.Strategy {
+	return agent.StrategyFunc(prog.choose)
+}
+
+func (prog *Program) choose(g *game.Game, moves *agent.Moves, rng *rand.Rand) int {
+	n := moves.Len()
+	idxs := make([]int, 0, n + 1) // Flip last
+	for i := range n {
+		idxs = append(idxs, i)
+	}
+	idxs = append(idxs, -1)
+
+	priorities := make([]float64, len(idxs))
+	weights := make([]float64, len(idxs))
+	anyPriority := false
+	for k,idx := range idxs {
+		e := newEnv(g, moves.At(idx))
+		priorities[k] = math.Inf(-1)
+		for _,r := range prog.rules {
+			if r.cond.eval(e) == 0 {
+				continue
+			}
+			if r.action == priority {
+				priorities[k] = max(priorities[k], r.value.eval(e))
+				anyPriority = true
+			} else {
+				weights[k] += max(r.value.eval(e), 0)
+			}
+		}
+	}
+
+	var candidates []int // Into idxs
+	if anyPriority {
+		best := slices.Max(priorities)
+		for k := range idxs {
+			if priorities[k] == best {
+				candidates = append(candidates, k)
+			}
+		}
+	} else {
+		for k := range idxs {
+			if weights[k] > 0 {
+				candidates = append(candidates, k)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return agent.Abstain
+	}
+
+	total := 0.0
+	for _,k := range candidates {
+		total += weights[k]
+	}
+	if total == 0 {
+		return idxs[candidates[0]]
+	}
+	r := rng.Float64() * total
+	for _,k := range candidates {
+		if weights[k] > 0 && r < weights[k] {
+			return idxs[k]
+		}
+		r -= weights[k]
+	}
+	return idxs[candidates[len(candidates) - 1]] // Rounding
+}
@@ -0,0 +1,91 @@
+package dsl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A parse or type error, at a position in a rules file (lines and columns count from 1)
+type Error struct {
+	File string
+	Line int
+	Col int
+	Msg string
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("%v:%v:%v: %v", err.File, err.Line, err.Col, err.Msg)
+}
+
+type tokenKind byte
+
+const (
+	tokEOL tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp // == != < <= > >= + - ( )
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	col int
+}
+
+func (tok token) String() string {
+	if tok.kind == tokEOL {
+		return "end of line"
+	}
+	return fmt.Sprintf("%q", tok.text)
+}
+
+// Split one line into tokens, ending with tokEOL. Comments start with #.
+func lex(file string, lineNo int, line string) ([]token,error) {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	var tokens []token
+	for i := 0; i < len(line); {
+		c := line[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case isDigit(c):
+			for i < len(line) && (isDigit(line[i]) || line[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, line[start:i], start + 1})
+		case isLetter(c):
+			for i < len(line) && (isLetter(line[i]) || isDigit(line[i]) || line[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, line[start:i], start + 1})
+		case strings.IndexByte("<>=!", c) >= 0:
+			i++
+			if i < len(line) && line[i] == '=' {
+				i++
+			}
+			op := line[start:i]
+			if op == "=" || op == "!" {
+				return nil,&Error{file, lineNo, start + 1, fmt.Sprintf("unknown operator %q (did you mean %q?)", op, op + "=")}
+			}
+			tokens = append(tokens, token{tokOp, op, start + 1})
+		case strings.IndexByte("+-()", c) >= 0:
+			i++
+			tokens = append(tokens, token{tokOp, line[start:i], start + 1})
+		default:
+			return nil,&Error{file, lineNo, start + 1, fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return append(tokens, token{tokEOL, "", len(line) + 1}), nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
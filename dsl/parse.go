@@ -0,0 +1,263 @@
+package dsl
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// A compiled expression. Bools are 1 or 0.
+type expr struct {
+	typ typ
+	eval func(e *env) float64
+}
+
+type action byte
+
+const (
+	priority action = iota
+	weight
+)
+
+func (a action) String() string {
+	if a == priority {
+		return "priority"
+	}
+	return "weight"
+}
+
+type rule struct {
+	line int
+	cond expr
+	action action
+	value expr
+}
+
+type parser struct {
+	file string
+	line int
+	tokens []token
+	pos int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOL {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorf(tok token, format string, args ...any) error {
+	return &Error{p.file, p.line, tok.col, fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) expect(text string) error {
+	if tok := p.next(); tok.text != text || tok.kind == tokEOL {
+		return p.errorf(tok, "expected %q, got %v", text, tok)
+	}
+	return nil
+}
+
+// rule := "when" expr "then" ("priority" | "weight") expr
+func (p *parser) rule() (rule,error) {
+	r := rule{line: p.line}
+	if err := p.expect("when"); err != nil {
+		return r,err
+	}
+	condTok := p.peek()
+	cond,err := p.or()
+	if err != nil {
+		return r,err
+	}
+	if cond.typ != tBool {
+		return r,p.errorf(condTok, "condition is a %v, expected a bool", cond.typ)
+	}
+	r.cond = cond
+	if err := p.expect("then"); err != nil {
+		return r,err
+	}
+
+	switch tok := p.next(); tok.text {
+	case "priority":
+		r.action = priority
+	case "weight":
+		r.action = weight
+	default:
+		return r,p.errorf(tok, "expected \"priority\" or \"weight\", got %v", tok)
+	}
+	valueTok := p.peek()
+	value,err := p.sum()
+	if err != nil {
+		return r,err
+	}
+	if value.typ != tNumber {
+		return r,p.errorf(valueTok, "%v is a %v, expected a number", r.action, value.typ)
+	}
+	r.value = value
+	if tok := p.next(); tok.kind != tokEOL {
+		return r,p.errorf(tok, "expected end of line, got %v", tok)
+	}
+	return r,nil
+}
+
+// Binary operators, loosest first
+func (p *parser) or() (expr,error) {
+	return p.logical("or", p.and, func(a, b bool) bool { return a || b })
+}
+
+func (p *parser) and() (expr,error) {
+	return p.logical("and", p.not, func(a, b bool) bool { return a && b })
+}
+
+func (p *parser) logical(op string, operand func() (expr,error), fn func(a, b bool) bool) (expr,error) {
+	tok := p.peek()
+	left,err := operand()
+	if err != nil {
+		return left,err
+	}
+	for p.peek().kind == tokIdent && p.peek().text == op {
+		p.next()
+		rightTok := p.peek()
+		right,err := operand()
+		if err != nil {
+			return right,err
+		}
+		if left.typ != tBool {
+			return left,p.errorf(tok, "left of %q is a %v, expected a bool", op, left.typ)
+		}
+		if right.typ != tBool {
+			return right,p.errorf(rightTok, "right of %q is a %v, expected a bool", op, right.typ)
+		}
+		l, r := left.eval, right.eval
+		left = expr{tBool, func(e *env) float64 { return boolNum(fn(l(e) != 0, r(e) != 0)) }}
+	}
+	return left,nil
+}
+
+func (p *parser) not() (expr,error) {
+	if tok := p.peek(); tok.kind == tokIdent && tok.text == "not" {
+		p.next()
+		operandTok := p.peek()
+		x,err := p.not()
+		if err != nil {
+			return x,err
+		}
+		if x.typ != tBool {
+			return x,p.errorf(operandTok, "operand of \"not\" is a %v, expected a bool", x.typ)
+		}
+		return expr{tBool, func(e *env) float64 { return 1 - x.eval(e) }}, nil
+	}
+	return p.comparison()
+}
+
+var comparisons = map[string]func(a, b float64) bool{
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+	"<": func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	">": func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+}
+
+func (p *parser) comparison() (expr,error) {
+	leftTok := p.peek()
+	left,err := p.sum()
+	if err != nil {
+		return left,err
+	}
+	cmp,ok := comparisons[p.peek().text]
+	if p.peek().kind != tokOp || !ok {
+		return left,nil
+	}
+	opTok := p.next()
+	right,err := p.sum()
+	if err != nil {
+		return right,err
+	}
+	if left.typ != right.typ {
+		return left,p.errorf(opTok, "can't compare a %v with a %v", left.typ, right.typ)
+	}
+	ordered := opTok.text != "==" && opTok.text != "!="
+	if ordered && left.typ != tNumber {
+		return left,p.errorf(leftTok, "%q needs numbers, got a %v", opTok.text, left.typ)
+	}
+	l, r := left.eval, right.eval
+	return expr{tBool, func(e *env) float64 { return boolNum(cmp(l(e), r(e))) }}, nil
+}
+
+func (p *parser) sum() (expr,error) {
+	leftTok := p.peek()
+	left,err := p.unary()
+	if err != nil {
+		return left,err
+	}
+	for p.peek().kind == tokOp && (p.peek().text == "+" || p.peek().text == "-") {
+		opTok := p.next()
+		rightTok := p.peek()
+		right,err := p.unary()
+		if err != nil {
+			return right,err
+		}
+		if left.typ != tNumber {
+			return left,p.errorf(leftTok, "left of %q is a %v, expected a number", opTok.text, left.typ)
+		}
+		if right.typ != tNumber {
+			return right,p.errorf(rightTok, "right of %q is a %v, expected a number", opTok.text, right.typ)
+		}
+		l, r := left.eval, right.eval
+		if opTok.text == "+" {
+			left = expr{tNumber, func(e *env) float64 { return l(e) + r(e) }}
+		} else {
+			left = expr{tNumber, func(e *env) float64 { return l(e) - r(e) }}
+		}
+	}
+	return left,nil
+}
+
+func (p *parser) unary() (expr,error) {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == "-" {
+		p.next()
+		x,err := p.unary()
+		if err != nil {
+			return x,err
+		}
+		if x.typ != tNumber {
+			return x,p.errorf(tok, "can't negate a %v", x.typ)
+		}
+		return expr{tNumber, func(e *env) float64 { return -x.eval(e) }}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (expr,error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		value,err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return expr{},p.errorf(tok, "bad number %q", tok.text)
+		}
+		return expr{tNumber, func(*env) float64 { return value }}, nil
+	case tokIdent:
+		if c,ok := constants[tok.text]; ok {
+			return expr{c.typ, func(*env) float64 { return c.value }}, nil
+		}
+		if v,ok := variables[tok.text]; ok {
+			return expr{v.typ, v.get}, nil
+		}
+		return expr{},p.errorf(tok, "unknown name %q", tok.text)
+	case tokOp:
+		if tok.text == "(" {
+			x,err := p.or()
+			if err != nil {
+				return x,err
+			}
+			return x,p.expect(")")
+		}
+	}
+	return expr{},p.errorf(tok, "expected a value, got %v", tok)
+}
@@ -0,0 +1,178 @@
+package dsl
+
+import (
+	"solitaire/agent"
+	"solitaire/deck"
+	"solitaire/game"
+)
+
+// Everything a rule can look at: one candidate move, and the board before it
+type env struct {
+	g *game.Game
+	move agent.Move
+	card deck.Card
+	hasCard bool // Flip doesn't move a card
+}
+
+func newEnv(g *game.Game, move agent.Move) *env {
+	e := &env{g: g, move: move, hasCard: true}
+	switch move.Kind {
+	case agent.Tableau:
+		queue := g.VisibleQueues[move.Src]
+		e.card = queue[len(queue) - 1] // The card that lands on Dst
+	case agent.AvailToTableau, agent.AvailToTop:
+		e.card,_ = g.PeekAvail()
+	case agent.ToTop:
+		e.card,_ = g.PeekQueue(move.Src)
+	case agent.FromTop:
+		e.card,_ = g.PeekSuit(move.Src)
+	default:
+		e.hasCard = false
+	}
+	return e
+}
+
+// The column cards leave, for Tableau and ToTop, or -1
+func (e *env) srcColumn() int {
+	if e.move.Kind == agent.Tableau || e.move.Kind == agent.ToTop {
+		return e.move.Src
+	}
+	return -1
+}
+
+type typ byte
+
+const (
+	tNumber typ = iota
+	tBool
+	tKind
+	tSuit
+)
+
+var typeNames = [...]string{tNumber: "number", tBool: "bool", tKind: "move kind", tSuit: "suit"}
+
+func (t typ) String() string {
+	return typeNames[t]
+}
+
+// Kinds and suits are numbers underneath, but can only be compared with their own type
+type variable struct {
+	typ typ
+	doc string
+	get func(e *env) float64
+}
+
+func boolNum(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+var variables = map[string]variable{
+	"move.kind": {tKind, "kind of move: Flip, Tableau, AvailToTableau, AvailToTop, ToTop or FromTop", func(e *env) float64 {
+		return float64(e.move.Kind)
+	}},
+	"move.src": {tNumber, "source column (Tableau, ToTop) or suit (FromTop), -1 otherwise", func(e *env) float64 {
+		return float64(e.move.Src)
+	}},
+	"move.dst": {tNumber, "destination column, -1 for moves to the foundation and Flip", func(e *env) float64 {
+		return float64(e.move.Dst)
+	}},
+	"move.reveals": {tBool, "the move turns up a face-down card", func(e *env) float64 {
+		col := e.srcColumn()
+		if col == -1 || len(e.g.HiddenStacks[col]) == 0 {
+			return 0
+		}
+		return boolNum(e.move.Kind == agent.Tableau || len(e.g.VisibleQueues[col]) == 1)
+	}},
+	"card.rank": {tNumber, "rank of the card moved, 1 (ace) to 13 (king), 0 for Flip", func(e *env) float64 {
+		if !e.hasCard {
+			return 0
+		}
+		return float64(e.card.Rank) + 1
+	}},
+	"card.suit": {tSuit, "suit of the card moved: Spades, Hearts, Clubs or Diamonds", func(e *env) float64 {
+		if !e.hasCard {
+			return -1
+		}
+		return float64(e.card.Suit)
+	}},
+	"card.red": {tBool, "the card moved is a heart or a diamond", func(e *env) float64 {
+		return boolNum(e.hasCard && e.card.Color() == 1)
+	}},
+	"card.foundation": {tNumber, "cards on the foundation of the moved card's suit", func(e *env) float64 {
+		if !e.hasCard {
+			return 0
+		}
+		return float64(e.g.SuitStacks[e.card.Suit])
+	}},
+	"src.hidden": {tNumber, "face-down cards in the source column (Tableau, ToTop), 0 otherwise", func(e *env) float64 {
+		if col := e.srcColumn(); col != -1 {
+			return float64(len(e.g.HiddenStacks[col]))
+		}
+		return 0
+	}},
+	"src.visible": {tNumber, "face-up cards in the source column (Tableau, ToTop), 0 otherwise", func(e *env) float64 {
+		if col := e.srcColumn(); col != -1 {
+			return float64(len(e.g.VisibleQueues[col]))
+		}
+		return 0
+	}},
+	"dst.empty": {tBool, "the destination column is empty", func(e *env) float64 {
+		return boolNum(e.move.Dst >= 0 && len(e.g.VisibleQueues[e.move.Dst]) == 0)
+	}},
+	"board.foundation": {tNumber, "cards on the foundation", func(e *env) float64 {
+		n := 0
+		for _,size := range e.g.SuitStacks {
+			n += size
+		}
+		return float64(n)
+	}},
+	"board.hidden": {tNumber, "face-down cards in the tableau", func(e *env) float64 {
+		n := 0
+		for _,stack := range e.g.HiddenStacks {
+			n += len(stack)
+		}
+		return float64(n)
+	}},
+	"board.empty_columns": {tNumber, "empty columns", func(e *env) float64 {
+		n := 0
+		for _,queue := range e.g.VisibleQueues {
+			if len(queue) == 0 {
+				n++
+			}
+		}
+		return float64(n)
+	}},
+	"board.deck": {tNumber, "cards left to flip in the Deck", func(e *env) float64 {
+		return float64(len(e.g.Deck))
+	}},
+	"board.avail": {tNumber, "cards in the Avail", func(e *env) float64 {
+		return float64(len(e.g.Avail))
+	}},
+	"board.passes": {tNumber, "times the Avail has been turned back over", func(e *env) float64 {
+		return float64(e.g.Passes)
+	}},
+	"foundation.min": {tNumber, "cards on the shortest foundation", func(e *env) float64 {
+		return float64(min(e.g.SuitStacks[0], e.g.SuitStacks[1], e.g.SuitStacks[2], e.g.SuitStacks[3]))
+	}},
+}
+
+var constants = map[string]struct {
+	typ typ
+	value float64
+}{
+	"true": {tBool, 1},
+	"false": {tBool, 0},
+	"Flip": {tKind, float64(agent.Flip)},
+	"Tableau": {tKind, float64(agent.Tableau)},
+	"AvailToTableau": {tKind, float64(agent.AvailToTableau)},
+	"AvailToTop": {tKind, float64(agent.AvailToTop)},
+	"ToTop": {tKind, float64(agent.ToTop)},
+	"FromTop": {tKind, float64(agent.FromTop)},
+	"Spades": {tSuit, float64(deck.Spades)},
+	"Hearts": {tSuit, float64(deck.Hearts)},
+	"Clubs": {tSuit, float64(deck.Clubs)},
+	"Diamonds": {tSuit, float64(deck.Diamonds)},
+}
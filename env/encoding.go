@@ -0,0 +1,104 @@
+package env
+
+import (
+	"solitaire/deck"
+	"solitaire/game"
+)
+
+// Numeric encoding of a position, built only from the game.View.
+// Bump EncodingVersion whenever the layout below changes, so old datasets can be told apart.
+//
+// Version 1 layout, NFeatures float32s:
+//
+//	[0, 624)    Card planes: 52 cards x 12 locations, one-hot per card. Card c = 13*suit + rank
+//	            (the order of deck.NewDeck) occupies [12c, 12c+12), with locations
+//	              0       foundation
+//	              1..7    face-up in tableau stack 0..6
+//	              8       unseen (face-down in the tableau, or in the Deck before the first pass)
+//	              9       top of the Avail
+//	              10      in the Avail under the top
+//	              11      in the Deck, seen on an earlier pass
+//	[624, 631)  Face-down cards in tableau stack 0..6, divided by 6
+//	[631]       Cards in the Deck, divided by 24
+//	[632]       Cards in the Avail, divided by 24
+//	[633]       Passes through the Deck used so far
+//	[634, 638)  Height of the foundation for each suit, divided by 13
+const EncodingVersion = 1
+
+const (
+	locFoundation = iota
+	locTableau
+	locUnseen = locTableau + game.NStacks
+	locAvailTop = locUnseen + 1
+	locAvail = locAvailTop + 1
+	locDeck = locAvail + 1
+	nLocations = locDeck + 1
+)
+
+const (
+	nCards = deck.NSuits * deck.SuitSize
+	planeFeatures = nCards * nLocations
+	hiddenFeatures = planeFeatures
+	deckFeature = hiddenFeatures + game.NStacks
+	availFeature = deckFeature + 1
+	passesFeature = availFeature + 1
+	foundationFeatures = passesFeature + 1
+	NFeatures = foundationFeatures + deck.NSuits
+)
+
+func Encode(view game.View) []float32 {
+	features := make([]float32, NFeatures)
+	EncodeInto(features, view)
+	return features
+}
+
+// Encode into `features`, which must have length NFeatures. Avoids allocating per position.
+func EncodeInto(features []float32, view game.View) {
+	if len(features) != NFeatures {
+		panic("EncodeInto: features must have length NFeatures")
+	}
+	clear(features)
+
+	var placed [nCards]bool
+	set := func(card deck.Card, loc int) {
+		c := card.Index()
+		placed[c] = true
+		features[c*nLocations + loc] = 1
+	}
+
+	for suit,size := range view.SuitStacks {
+		for rank := range size {
+			set(deck.NewCard(rank, suit), locFoundation)
+		}
+	}
+	for i,queue := range view.VisibleQueues {
+		for _,card := range queue {
+			set(card, locTableau + i)
+		}
+	}
+	for i,card := range view.Avail {
+		if i == len(view.Avail) - 1 {
+			set(card, locAvailTop)
+		} else {
+			set(card, locAvail)
+		}
+	}
+	for _,card := range view.Deck {
+		set(card, locDeck)
+	}
+	for c := range nCards {
+		if !placed[c] {
+			features[c*nLocations + locUnseen] = 1
+		}
+	}
+
+	for i,n := range view.NHidden {
+		features[hiddenFeatures + i] = float32(n) / float32(game.NStacks - 1)
+	}
+	features[deckFeature] = float32(view.NDeck) / 24
+	features[availFeature] = float32(len(view.Avail)) / 24
+	features[passesFeature] = float32(view.Passes)
+	for suit,size := range view.SuitStacks {
+		features[foundationFeatures + suit] = float32(size) / float32(deck.SuitSize)
+	}
+}
@@ -0,0 +1,197 @@
+package env
+
+import (
+	"solitaire/agent"
+	"solitaire/deck"
+	"solitaire/game"
+	"solitaire/sim"
+)
+
+// A reinforcement learning environment around game.Game, in the style of a gym env.
+//
+// Actions are numbered in a fixed space of NActions, whatever the position:
+//
+//	0                    Flip
+//	1  + 7*src + dst     Tableau move (whole visible queue) from stack src to stack dst
+//	50 + dst             Avail to stack dst
+//	57                   Avail to top
+//	58 + src             Stack src to top
+//	65 + 7*suit + dst    Top of suit stack to stack dst
+//
+// Only the moves an agent would consider (see agent.FindMoves) are legal.
+const (
+	flipAction = 0
+	tableauActions = flipAction + 1
+	availActions = tableauActions + game.NStacks*game.NStacks
+	availToTopAction = availActions + game.NStacks
+	toTopActions = availToTopAction + 1
+	fromTopActions = toTopActions + game.NStacks
+	NActions = fromTopActions + deck.NSuits*game.NStacks
+)
+
+// The View's slices belong to the Env and are overwritten by the next Step or Reset, so
+// that stepping doesn't allocate. Copy them to keep them.
+type Observation struct {
+	game.View
+	Legal [NActions]bool // Flip is always legal
+}
+
+// Summary of a position used to compute rewards
+type Progress struct {
+	NFoundation int
+	NHidden int
+	Won bool
+}
+
+// Reward for going from one position to the next
+type Reward func(before, after Progress) float64
+
+// 1 for the move that wins the game, 0 otherwise
+func WinOnly(before, after Progress) float64 {
+	if after.Won && !before.Won {
+		return 1
+	}
+	return 0
+}
+
+// Change in the number of cards on the foundation (can be negative)
+func FoundationCards(before, after Progress) float64 {
+	return float64(after.NFoundation - before.NFoundation)
+}
+
+// Number of face-down cards turned up
+func CardsRevealed(before, after Progress) float64 {
+	return float64(before.NHidden - after.NHidden)
+}
+
+type Info struct {
+	Illegal bool // The action was not legal and nothing happened
+	Truncated bool // Stopped at sim.MaxMoves rather than by winning or getting stuck
+	Steps int
+}
+
+type Env struct {
+	Reward Reward
+	Rules game.Rules // For the next Reset
+	game *game.Game
+	finder agent.MoveFinder
+	view game.View
+	moves agent.Moves
+	moveIdx [NActions]int // Action -> index in moves (-1 for Flip)
+	legal [NActions]bool
+	steps int
+	turnsWithoutMove int
+	done bool
+}
+
+func New(reward Reward) *Env {
+	return NewWithRules(reward, game.DefaultRules)
+}
+
+func NewWithRules(reward Reward, rules game.Rules) *Env {
+	return &Env{Reward: reward, Rules: rules}
+}
+
+// Start a new episode on the deal for `seed` (see sim.Deal)
+func (env *Env) Reset(seed int64) Observation {
+	env.game = game.NewGameWithRules(sim.Deal(seed), env.Rules)
+	env.steps = 0
+	env.turnsWithoutMove = 0
+	env.done = false
+	env.findMoves()
+	return env.observe()
+}
+
+// Play `action`. The episode is done when the game is won, or when it stops making
+// progress under the same rule the simulator uses.
+func (env *Env) Step(action int) (Observation, float64, bool, Info) {
+	if env.done || action < 0 || action >= NActions || !env.legal[action] {
+		return env.observe(), 0, env.done, Info{Illegal: true, Steps: env.steps}
+	}
+
+	before := env.progress()
+	agent.ExecuteMove(env.game, env.moves, env.moveIdx[action])
+	after := env.progress()
+	env.steps++
+
+	if action == flipAction {
+		env.turnsWithoutMove++
+	} else {
+		env.turnsWithoutMove = 0
+	}
+	stuck := env.turnsWithoutMove >= max(len(env.game.Avail) + len(env.game.Deck), 10)
+	truncated := env.steps >= sim.MaxMoves
+	env.done = after.Won || stuck || truncated
+
+	env.findMoves()
+	return env.observe(), env.Reward(before, after), env.done, Info{Truncated: truncated && !after.Won && !stuck, Steps: env.steps}
+}
+
+// The underlying game, e.g. for display. Looking at it is cheating!
+func (env *Env) Game() *game.Game {
+	return env.game
+}
+
+func (env *Env) progress() Progress {
+	p := Progress{Won: env.game.IsWon()}
+	for _,size := range env.game.SuitStacks {
+		p.NFoundation += size
+	}
+	for _,stack := range env.game.HiddenStacks {
+		p.NHidden += len(stack)
+	}
+	return p
+}
+
+func (env *Env) findMoves() {
+	env.finder.Find(env.game, &env.moves)
+	env.legal = [NActions]bool{}
+	env.legal[flipAction] = true
+	env.moveIdx[flipAction] = -1
+	for i := range env.moves.Len() {
+		action := ActionID(env.moves.At(i))
+		env.legal[action] = true
+		env.moveIdx[action] = i
+	}
+}
+
+func (env *Env) observe() Observation {
+	env.game.ViewInto(&env.view)
+	return Observation{View: env.view, Legal: env.legal}
+}
+
+// The action number of `move`
+func ActionID(move agent.Move) int {
+	switch move.Kind {
+	case agent.Tableau:
+		return tableauActions + game.NStacks*move.Src + move.Dst
+	case agent.AvailToTableau:
+		return availActions + move.Dst
+	case agent.AvailToTop:
+		return availToTopAction
+	case agent.ToTop:
+		return toTopActions + move.Src
+	case agent.FromTop:
+		return fromTopActions + game.NStacks*move.Src + move.Dst
+	}
+	return flipAction
+}
+
+// The move for action number `action`, the inverse of ActionID
+func ActionMove(action int) agent.Move {
+	switch {
+	case action >= fromTopActions:
+		a := action - fromTopActions
+		return agent.Move{Kind: agent.FromTop, Src: a / game.NStacks, Dst: a % game.NStacks}
+	case action >= toTopActions:
+		return agent.Move{Kind: agent.ToTop, Src: action - toTopActions, Dst: -1}
+	case action == availToTopAction:
+		return agent.Move{Kind: agent.AvailToTop, Src: -1, Dst: -1}
+	case action >= availActions:
+		return agent.Move{Kind: agent.AvailToTableau, Src: -1, Dst: action - availActions}
+	case action >= tableauActions:
+		a := action - tableauActions
+		return agent.Move{Kind: agent.Tableau, Src: a / game.NStacks, Dst: a % game.NStacks}
+	}
+	return agent.Move{Kind: agent.Flip, Src: -1, Dst: -1}
+}
@@ -0,0 +1,120 @@
+package env
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Writers for NumPy's .npy format (version 1.0), little-endian float32 only.
+// See https://numpy.org/doc/stable/reference/generated/numpy.lib.format.html
+
+var npyMagic = []byte("\x93NUMPY\x01\x00")
+
+// Total header size. Fixed, so the shape can be patched in once the row count is known.
+const npyHeaderSize = 128
+
+func npyHeader(shape []int) ([]byte,error) {
+	dims := make([]string, len(shape))
+	for i,d := range shape {
+		dims[i] = fmt.Sprint(d)
+	}
+	shapeStr := strings.Join(dims, ", ")
+	if len(shape) == 1 {
+		shapeStr += ","
+	}
+	dict := fmt.Sprintf("{'descr': '<f4', 'fortran_order': False, 'shape': (%v), }", shapeStr)
+
+	headerLen := npyHeaderSize - len(npyMagic) - 2
+	if len(dict) + 1 > headerLen {
+		return nil,errors.New("npy: shape too long for header")
+	}
+	header := make([]byte, 0, npyHeaderSize)
+	header = append(header, npyMagic...)
+	header = binary.LittleEndian.AppendUint16(header, uint16(headerLen))
+	header = append(header, dict...)
+	for len(header) < npyHeaderSize - 1 {
+		header = append(header, ' ')
+	}
+	return append(header, '\n'),nil
+}
+
+// Write `data` as a single array with the given shape
+func WriteNPY(w io.Writer, data []float32, shape ...int) error {
+	n := 1
+	for _,d := range shape {
+		n *= d
+	}
+	if n != len(data) {
+		return fmt.Errorf("npy: shape %v does not match %v values", shape, len(data))
+	}
+
+	header,err := npyHeader(shape)
+	if err != nil {
+		return err
+	}
+	if _,err := w.Write(header); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, data)
+}
+
+// Streams rows of a 2-D array of unknown length to a file, fixing up the shape on Close
+type NPYWriter struct {
+	f io.WriteSeeker
+	buf *bufio.Writer
+	cols int
+	rows int
+}
+
+func NewNPYWriter(f io.WriteSeeker, cols int) (*NPYWriter,error) {
+	w := &NPYWriter{f: f, buf: bufio.NewWriter(f), cols: cols}
+	header,err := npyHeader([]int{0, cols})
+	if err != nil {
+		return nil,err
+	}
+	_,err = w.buf.Write(header)
+	return w,err
+}
+
+func (w *NPYWriter) WriteRow(row []float32) error {
+	if len(row) != w.cols {
+		return fmt.Errorf("npy: row has %v values, expected %v", len(row), w.cols)
+	}
+	var b [4]byte
+	for _,x := range row {
+		binary.LittleEndian.PutUint32(b[:], math.Float32bits(x))
+		if _,err := w.buf.Write(b[:]); err != nil {
+			return err
+		}
+	}
+	w.rows++
+	return nil
+}
+
+func (w *NPYWriter) Rows() int {
+	return w.rows
+}
+
+// Flush and rewrite the header with the final number of rows. Does not close the file.
+func (w *NPYWriter) Close() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	header,err := npyHeader([]int{w.rows, w.cols})
+	if err != nil {
+		return err
+	}
+	if _,err := w.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _,err := w.f.Write(header); err != nil {
+		return err
+	}
+	_,err = w.f.Seek(0, io.SeekEnd)
+	return err
+}
@@ -0,0 +1,115 @@
+package eval
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"solitaire/agent"
+	"solitaire/game"
+)
+
+// A weighted sum of Terms. Configs are JSON, e.g.
+//
+//	{
+//	  "full": false,
+//	  "weights": {"foundation": 1, "hidden": -1, "blocked_low": -0.2}
+//	}
+//
+// With Full set, terms see the whole game, face-down cards included: for "thoughtful"
+// solitaire, where the player knows where everything is.
+type Evaluator struct {
+	Full bool `json:"full"`
+	Weights map[string]float64 `json:"weights"`
+}
+
+// Same as agent.ProgressEval
+var Default = Evaluator{Weights: map[string]float64{"foundation": 1, "hidden": -1}}
+
+// Term names, sorted, so sums always add up in the same order
+func TermNames() []string {
+	names := make([]string, 0, len(Terms))
+	for name := range Terms {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (e Evaluator) Validate() error {
+	for name := range e.Weights {
+		if Terms[name] == nil {
+			return fmt.Errorf("unknown eval term %q, expected one of %v", name, TermNames())
+		}
+	}
+	return nil
+}
+
+func Load(path string) (Evaluator,error) {
+	data,err := os.ReadFile(path)
+	if err != nil {
+		return Evaluator{},err
+	}
+	return parse(path, data)
+}
+
+//go:embed *.json
+var presets embed.FS
+
+// Configs shipped with the code, by file name without .json, e.g. reachable_waste
+func Presets() []string {
+	entries,_ := presets.ReadDir(".")
+	var names []string
+	for _,e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	return names
+}
+
+// A preset by name, or else the config file at `path`
+func Open(path string) (Evaluator,error) {
+	if data,err := presets.ReadFile(path + ".json"); err == nil {
+		return parse(path, data)
+	}
+	return Load(path)
+}
+
+func parse(path string, data []byte) (Evaluator,error) {
+	var e Evaluator
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Evaluator{},fmt.Errorf("%v: %w", path, err)
+	}
+	if err := e.Validate(); err != nil {
+		return Evaluator{},fmt.Errorf("%v: %w", path, err)
+	}
+	return e,nil
+}
+
+func (e Evaluator) Save(path string) error {
+	data,err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (e Evaluator) Eval(g *game.Game) float64 {
+	p := &Position{Game: g, Full: e.Full}
+	total := 0.0
+	for _,name := range termOrder {
+		if w,ok := e.Weights[name]; ok && w != 0 {
+			total += w * Terms[name](p)
+		}
+	}
+	return total
+}
+
+// For the search strategies, e.g. agent.ExpectimaxStrategy{..., Eval: e.Func()}
+func (e Evaluator) Func() agent.EvalFunc {
+	return e.Eval
+}
+
+var termOrder = TermNames()
@@ -0,0 +1,214 @@
+package eval
+
+import (
+	"solitaire/agent"
+	"solitaire/belief"
+	"solitaire/deck"
+	"solitaire/game"
+)
+
+// The position a term is scoring. Unless Full, terms may only use what the player can see:
+// how many cards are face down, but not which, and the same for the Deck until it has
+// been through once. Where a term needs to know about unseen cards it uses their expected
+// value over where they might be (see belief).
+type Position struct {
+	Game *game.Game
+	Full bool
+	belief *belief.Belief
+}
+
+func (p *Position) Belief() *belief.Belief {
+	if p.belief == nil {
+		p.belief = belief.New(p.Game.View())
+	}
+	return p.belief
+}
+
+// One feature of a position. Each is scaled to be roughly between 0 and 1, so that weights
+// are comparable.
+type Term func(p *Position) float64
+
+// Terms by the name used for them in config files
+var Terms = map[string]Term{
+	"foundation": Foundation,
+	"hidden": Hidden,
+	"hidden_depth": HiddenDepth,
+	"empty_columns": EmptyColumns,
+	"kings_bottom": KingsAtBottom,
+	"blocked_low": BlockedLow,
+	"reachable_waste": ReachableWaste,
+	"inversions": Inversions,
+}
+
+// Cards on the foundation, out of 52
+func Foundation(p *Position) float64 {
+	n := 0
+	for _,size := range p.Game.SuitStacks {
+		n += size
+	}
+	return float64(n) / 52
+}
+
+// Face-down cards left, out of the 21 dealt
+func Hidden(p *Position) float64 {
+	n := 0
+	for _,stack := range p.Game.HiddenStacks {
+		n += len(stack)
+	}
+	return float64(n) / 21
+}
+
+// For each face-down card, the number of cards on top of it, out of 56 for a new deal.
+// Grows when cards are piled onto columns that still have cards to dig out.
+func HiddenDepth(p *Position) float64 {
+	n := 0
+	for i,stack := range p.Game.HiddenStacks {
+		for j := range stack {
+			n += len(stack) - 1 - j + len(p.Game.VisibleQueues[i])
+		}
+	}
+	return float64(n) / 56
+}
+
+func EmptyColumns(p *Position) float64 {
+	n := 0
+	for _,queue := range p.Game.VisibleQueues {
+		if len(queue) == 0 {
+			n++
+		}
+	}
+	return float64(n) / game.NStacks
+}
+
+// Kings at the bottom of a column, with nothing under them, out of 4
+func KingsAtBottom(p *Position) float64 {
+	n := 0
+	for i,queue := range p.Game.VisibleQueues {
+		if len(queue) > 0 && len(p.Game.HiddenStacks[i]) == 0 && queue[len(queue) - 1].Rank == deck.King {
+			n++
+		}
+	}
+	return float64(n) / 4
+}
+
+// Aces and twos stuck in the tableau: face down, or face up with other cards on them.
+// Out of 8.
+func BlockedLow(p *Position) float64 {
+	g := p.Game
+	n := 0.0
+	for _,queue := range g.VisibleQueues {
+		for _,c := range queue[min(1, len(queue)):] {
+			if c.Rank <= deck.Two {
+				n++
+			}
+		}
+	}
+	if p.Full {
+		for _,stack := range g.HiddenStacks {
+			for _,c := range stack {
+				if c.Rank <= deck.Two {
+					n++
+				}
+			}
+		}
+		return n / 8
+	}
+
+	b := p.Belief()
+	for _,c := range b.Unseen() {
+		if c.Rank <= deck.Two {
+			for pile := range game.NStacks {
+				n += b.ProbInPile(c, pile)
+			}
+		}
+	}
+	return n / 8
+}
+
+// Distinct cards we can get to from the stock this pass or next (see
+// agent.AvailCards) that could be played right now, out of the 24 dealt to the stock
+func ReachableWaste(p *Position) float64 {
+	g := p.Game
+	cards,_ := agent.AvailCards(g)
+
+	inAvail := make(map[deck.Card]bool, len(g.Avail))
+	for _,c := range g.Avail {
+		inAvail[c] = true
+	}
+	counted := make(map[deck.Card]bool, len(cards))
+	n := 0
+	for _,c := range cards {
+		if counted[c] || (!p.Full && !inAvail[c] && !g.DeckSeen()) {
+			continue
+		}
+		counted[c] = true
+		if canPush,_ := g.CanPushSuit(c); canPush {
+			n++
+			continue
+		}
+		for i := range game.NStacks {
+			front,err := g.PeekQueue(i)
+			if (err == nil && deck.CanPlace(c, front)) || (err != nil && c.Rank == deck.King) {
+				n++
+				break
+			}
+		}
+	}
+	return float64(n) / 24
+}
+
+// Pairs of same-suit cards in a column with the higher card on top of the lower one, so
+// that it has to be moved off before the lower one can go up. Out of the 56 pairs of
+// cards in the same column in a new deal.
+func Inversions(p *Position) float64 {
+	g := p.Game
+	n := 0.0
+	for i := range game.NStacks {
+		// Bottom to top. Only the visible part is known to the player.
+		var column []deck.Card
+		if p.Full {
+			column = append(column, g.HiddenStacks[i]...)
+		}
+		for j := len(g.VisibleQueues[i]) - 1; j >= 0; j-- {
+			column = append(column, g.VisibleQueues[i][j])
+		}
+		for a,lower := range column {
+			for _,higher := range column[a+1:] {
+				if lower.Suit == higher.Suit && lower.Rank < higher.Rank {
+					n++
+				}
+			}
+		}
+	}
+	if p.Full {
+		return n / 56
+	}
+
+	// Expected inversions involving face-down cards: every face-down card is a random one
+	// of the unseen cards
+	unseen := p.Belief().Unseen()
+	nUnseen := float64(len(unseen))
+	var below [deck.NSuits][deck.SuitSize]int // Unseen cards of the suit with lower rank
+	for _,c := range unseen {
+		for r := int(c.Rank) + 1; r < deck.SuitSize; r++ {
+			below[c.Suit][r]++
+		}
+	}
+	nOrdered := 0 // Pairs of unseen cards that would be an inversion
+	for _,c := range unseen {
+		nOrdered += below[c.Suit][c.Rank]
+	}
+	for i,stack := range g.HiddenStacks {
+		h := float64(len(stack))
+		if h == 0 {
+			continue
+		}
+		for _,c := range g.VisibleQueues[i] {
+			n += h * float64(below[c.Suit][c.Rank]) / nUnseen
+		}
+		if h > 1 {
+			n += h * (h - 1) / 2 * float64(nOrdered) / (nUnseen * (nUnseen - 1))
+		}
+	}
+	return n / 56
+}
@@ -0,0 +1,98 @@
+package experiment
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"solitaire/game"
+)
+
+// What was run, and with what code, so that a result can be reproduced and compared
+// long after. Written to manifest.json in the run's directory.
+type Manifest struct {
+	Command string `json:"command"` // e.g. "sim"
+	Strategy string `json:"strategy"`
+	Params map[string]string `json:"params"` // All of them, defaults included
+	Rules string `json:"rules"`
+	FirstSeed int64 `json:"first_seed"`
+	Games int `json:"games"` // Seeds FirstSeed to FirstSeed+Games-1
+	Revision string `json:"revision"` // Git commit, "unknown" outside a checkout
+	Modified bool `json:"modified"` // Uncommitted changes on top of Revision
+	GoVersion string `json:"go_version"`
+	Start time.Time `json:"start"`
+	End time.Time `json:"end"` // Zero until the run finishes
+}
+
+func NewManifest(command, strategy string, params map[string]string, rules game.Rules, firstSeed int64, games int) Manifest {
+	m := Manifest{
+		Command: command,
+		Strategy: strategy,
+		Params: params,
+		Rules: rules.String(),
+		FirstSeed: firstSeed,
+		Games: games,
+		GoVersion: runtime.Version(),
+		Start: time.Now().UTC(),
+	}
+	m.Revision,m.Modified = revision()
+	return m
+}
+
+// Built binaries know their commit. `go run` doesn't stamp it, so ask git.
+func revision() (string,bool) {
+	if info,ok := debug.ReadBuildInfo(); ok {
+		rev, modified := "", false
+		for _,s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				rev = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		if rev != "" {
+			return rev,modified
+		}
+	}
+	out,err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "unknown",false
+	}
+	status,err := exec.Command("git", "status", "--porcelain", "--untracked-files=no").Output()
+	return strings.TrimSpace(string(out)), err == nil && len(status) > 0
+}
+
+func ReadManifest(path string) (Manifest,error) {
+	var m Manifest
+	return m,readJSON(path, &m)
+}
+
+func readJSON(path string, v any) error {
+	data,err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%v: %w", path, err)
+	}
+	return nil
+}
+
+// Written to a temporary file first, so that an interrupted write leaves the old file
+func writeJSON(path string, v any) error {
+	data,err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,172 @@
+package experiment
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"solitaire/agent"
+	"solitaire/deck"
+	"solitaire/sim"
+)
+
+// One game of a run, see sim.GameResult
+type Row struct {
+	Seed int64 `json:"seed"`
+	Won bool `json:"won"`
+	Moves int `json:"moves"` // Including flips
+	ByKind map[string]int `json:"by_kind"` // Moves of each agent.MoveKind, by name
+	Passes int `json:"passes"`
+	Foundation int `json:"foundation"` // Cards on the foundation at the end
+	FoundationTop [deck.NSuits]int `json:"foundation_top"` // Highest rank on each, by suit
+	Revealed int `json:"revealed"` // Of the cards dealt face down
+	NoProgress int `json:"no_progress"`
+	StalledAt int `json:"stalled_at"`
+	End sim.End `json:"end"`
+	Millis float64 `json:"ms"`
+}
+
+func NewRow(seed int64, r sim.GameResult) Row {
+	row := Row{
+		Seed: seed,
+		Won: r.Won,
+		Moves: r.Moves,
+		ByKind: make(map[string]int, len(r.ByKind)),
+		Passes: r.Passes,
+		Foundation: r.Foundation,
+		FoundationTop: r.FoundationTop,
+		Revealed: r.Revealed,
+		NoProgress: r.NoProgress,
+		StalledAt: r.StalledAt,
+		End: r.End,
+		Millis: float64(r.Duration.Microseconds()) / 1000,
+	}
+	for kind,n := range r.ByKind {
+		row.ByKind[agent.MoveKind(kind).String()] = n
+	}
+	return row
+}
+
+type Format string
+
+const (
+	CSV Format = "csv"
+	JSONL Format = "jsonl"
+)
+
+func ParseFormat(s string) (Format,error) {
+	switch f := Format(s); f {
+	case CSV, JSONL:
+		return f,nil
+	}
+	return "",fmt.Errorf("unknown row format %q, expected csv or jsonl", s)
+}
+
+type RowWriter interface {
+	Write(Row) error
+	Flush() error // Get everything written so far into the file
+	Close() error
+}
+
+// Moves by kind and the foundation tops get a column each, e.g. Flip and top_spades
+var csvHeader = func() []string {
+	h := []string{"seed", "won", "moves"}
+	for kind := range agent.NMoveKinds {
+		h = append(h, agent.MoveKind(kind).String())
+	}
+	h = append(h, "passes", "foundation")
+	for _,suit := range []string{"spades", "hearts", "clubs", "diamonds"} {
+		h = append(h, "top_" + suit)
+	}
+	return append(h, "revealed", "no_progress", "stalled_at", "end", "ms")
+}()
+
+type csvWriter struct {
+	f *os.File
+	w *csv.Writer
+}
+
+type jsonlWriter struct {
+	f *os.File
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+// Rows go to games.csv or games.jsonl in `dir`. With `appending`, rows are added to an
+// existing file rather than starting a new one.
+func NewRowWriter(dir string, format Format, appending bool) (RowWriter,error) {
+	path := RowsPath(dir, format)
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appending {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f,err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil,err
+	}
+	if format == JSONL {
+		buf := bufio.NewWriter(f)
+		return &jsonlWriter{f: f, buf: buf, enc: json.NewEncoder(buf)}, nil
+	}
+	w := &csvWriter{f: f, w: csv.NewWriter(f)}
+	if info,err := f.Stat(); err == nil && info.Size() == 0 {
+		w.w.Write(csvHeader)
+	}
+	return w,nil
+}
+
+func RowsPath(dir string, format Format) string {
+	return filepath.Join(dir, "games." + string(format))
+}
+
+func (w *csvWriter) Write(r Row) error {
+	record := []string{strconv.FormatInt(r.Seed, 10), strconv.FormatBool(r.Won), strconv.Itoa(r.Moves)}
+	for kind := range agent.NMoveKinds {
+		record = append(record, strconv.Itoa(r.ByKind[agent.MoveKind(kind).String()]))
+	}
+	record = append(record, strconv.Itoa(r.Passes), strconv.Itoa(r.Foundation))
+	for _,top := range r.FoundationTop {
+		record = append(record, strconv.Itoa(top))
+	}
+	return w.w.Write(append(record,
+		strconv.Itoa(r.Revealed),
+		strconv.Itoa(r.NoProgress),
+		strconv.Itoa(r.StalledAt),
+		r.End.String(),
+		strconv.FormatFloat(r.Millis, 'f', 3, 64),
+	))
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	if err := w.w.Error(); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+func (w *jsonlWriter) Write(r Row) error {
+	return w.enc.Encode(r)
+}
+
+func (w *jsonlWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+func (w *jsonlWriter) Close() error {
+	if err := w.buf.Flush(); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
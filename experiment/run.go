@@ -0,0 +1,182 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// An experiment's output directory:
+//
+//	manifest.json       how it was run, see Manifest
+//	games.csv (.jsonl)  one Row per game, in seed order
+//	checkpoint.json     how far the run got, see Checkpoint
+//	summary.json        see Summary, written when the run finishes
+//
+// Games have to be added in seed order, so that the games done are always the first
+// so many seeds and an interrupted run can carry on where its last checkpoint was.
+type Run struct {
+	Dir string
+	Manifest Manifest
+	Summary Summary
+	format Format
+	rows RowWriter
+	done int
+	elapsed time.Duration // In earlier sessions of a resumed run
+	started time.Time // This session
+}
+
+// How far a run got. Rows written after it are thrown away on resuming, since the
+// summary doesn't include them.
+type checkpoint struct {
+	Games int `json:"games"` // Done: seeds FirstSeed to FirstSeed+Games-1
+	RowsSize int64 `json:"rows_size"` // Bytes of the rows file holding them
+	Format Format `json:"format"`
+	Summary Summary `json:"summary"`
+}
+
+func (run *Run) checkpointPath() string {
+	return filepath.Join(run.Dir, "checkpoint.json")
+}
+
+// A new run in `dir`, replacing whatever was there
+func Create(dir string, m Manifest, format Format) (*Run,error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil,err
+	}
+	run := &Run{Dir: dir, Manifest: m, format: format, started: time.Now()}
+	if err := os.Remove(run.checkpointPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil,err
+	}
+	if err := writeJSON(filepath.Join(dir, "manifest.json"), m); err != nil {
+		return nil,err
+	}
+	var err error
+	if run.rows,err = NewRowWriter(dir, format, false); err != nil {
+		return nil,err
+	}
+	return run,nil
+}
+
+// Start a run in `dir`, or carry on from the last checkpoint of the one there. It has to
+// be the same run, down to the revision of the code (with nothing uncommitted), or the
+// results could differ.
+func Start(dir string, m Manifest, format Format) (*Run,error) {
+	old,err := ReadManifest(filepath.Join(dir, "manifest.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Create(dir, m, format)
+	}
+	if err != nil {
+		return nil,err
+	}
+	if err := old.sameRun(m); err != nil {
+		return nil,fmt.Errorf("%v holds a different run (%w), use another directory", dir, err)
+	}
+
+	run := &Run{Dir: dir, Manifest: old, format: format, started: time.Now()}
+	var ckpt checkpoint
+	if err := readJSON(run.checkpointPath(), &ckpt); errors.Is(err, fs.ErrNotExist) {
+		return Create(dir, m, format) // Stopped before its first checkpoint
+	} else if err != nil {
+		return nil,err
+	}
+	if ckpt.Format != format {
+		return nil,fmt.Errorf("%v has rows in %v, not %v", dir, ckpt.Format, format)
+	}
+	if err := os.Truncate(RowsPath(dir, format), ckpt.RowsSize); err != nil {
+		return nil,err
+	}
+	if run.rows,err = NewRowWriter(dir, format, true); err != nil {
+		return nil,err
+	}
+	run.Summary = ckpt.Summary
+	run.done = ckpt.Games
+	run.elapsed = time.Duration(ckpt.Summary.Seconds * float64(time.Second))
+	run.Manifest.End = time.Time{}
+	return run,nil
+}
+
+// Everything that decides a run's results
+func (m Manifest) sameRun(o Manifest) error {
+	switch {
+	case m.Command != o.Command:
+		return fmt.Errorf("command %v, not %v", m.Command, o.Command)
+	case m.Strategy != o.Strategy || !maps.Equal(m.Params, o.Params):
+		return fmt.Errorf("strategy %v %v, not %v %v", m.Strategy, m.Params, o.Strategy, o.Params)
+	case m.Rules != o.Rules:
+		return fmt.Errorf("rules %v, not %v", m.Rules, o.Rules)
+	case m.FirstSeed != o.FirstSeed || m.Games != o.Games:
+		return fmt.Errorf("%v games from seed %v, not %v from %v", m.Games, m.FirstSeed, o.Games, o.FirstSeed)
+	case m.Revision != o.Revision:
+		return fmt.Errorf("revision %v, not %v", m.Revision, o.Revision)
+	case m.Modified || o.Modified:
+		// The revision doesn't say what the uncommitted changes were
+		return fmt.Errorf("uncommitted changes to the code, so it may not be the same")
+	}
+	return nil
+}
+
+// Games done so far, including those of earlier sessions
+func (run *Run) Done() int {
+	return run.done
+}
+
+// The game for the next seed
+func (run *Run) Add(row Row) error {
+	if want := run.Manifest.FirstSeed + int64(run.done); row.Seed != want {
+		return fmt.Errorf("game for seed %v added when the next is %v", row.Seed, want)
+	}
+	run.done++
+	run.Summary.Add(row)
+	return run.rows.Write(row)
+}
+
+// Save how far the run has got
+func (run *Run) Checkpoint() error {
+	if err := run.rows.Flush(); err != nil {
+		return err
+	}
+	info,err := os.Stat(RowsPath(run.Dir, run.format))
+	if err != nil {
+		return err
+	}
+	run.Summary.Seconds = run.seconds()
+	return writeJSON(run.checkpointPath(), checkpoint{
+		Games: run.done,
+		RowsSize: info.Size(),
+		Format: run.format,
+		Summary: run.Summary,
+	})
+}
+
+// Time spent running, not counting time between sessions
+func (run *Run) seconds() float64 {
+	return (run.elapsed + time.Since(run.started)).Seconds()
+}
+
+// Finish the run: close the rows and write the summary and the end time. A run that
+// hasn't done all its games gets a checkpoint instead, to resume from.
+func (run *Run) Close() error {
+	if run.done < run.Manifest.Games {
+		err := run.Checkpoint()
+		if closeErr := run.rows.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+	if err := run.Checkpoint(); err != nil {
+		return err
+	}
+	if err := run.rows.Close(); err != nil {
+		return err
+	}
+	run.Manifest.End = time.Now().UTC()
+	if err := writeJSON(filepath.Join(run.Dir, "manifest.json"), run.Manifest); err != nil {
+		return err
+	}
+	return writeJSON(filepath.Join(run.Dir, "summary.json"), run.Summary)
+}
@@ -0,0 +1,251 @@
+package experiment
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Results of past runs, kept in a directory:
+//
+//	runs.jsonl  every run ever stored, one Entry per line, only ever appended to
+//	index.json  where each entry is in runs.jsonl, with what queries filter on most
+//
+// The log is the truth. The index is rebuilt from it when missing, and catches up with
+// entries appended since it was written, e.g. by another process.
+type Store struct {
+	dir string
+}
+
+// One stored run
+type Entry struct {
+	ID string `json:"id"`
+	Manifest Manifest `json:"manifest"`
+	Summary Summary `json:"summary"`
+}
+
+type Filter struct {
+	Command string // All empty or zero fields match anything
+	Strategy string
+	Rules string
+	Params map[string]string // Entries must have these values, others can be anything
+	Since time.Time
+	Until time.Time
+}
+
+type index struct {
+	LogSize int64 `json:"log_size"` // Bytes of runs.jsonl indexed
+	Entries []indexEntry `json:"entries"`
+}
+
+type indexEntry struct {
+	ID string `json:"id"`
+	Command string `json:"command"`
+	Strategy string `json:"strategy"`
+	Rules string `json:"rules"`
+	Start time.Time `json:"start"`
+	Offset int64 `json:"offset"`
+}
+
+// $SOLITAIRE_RESULTS, or results in the current directory
+func DefaultStoreDir() string {
+	if dir := os.Getenv("SOLITAIRE_RESULTS"); dir != "" {
+		return dir
+	}
+	return "results"
+}
+
+func OpenStore(dir string) (*Store,error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil,err
+	}
+	return &Store{dir: dir}, nil
+}
+
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+func (s *Store) logPath() string {
+	return filepath.Join(s.dir, "runs.jsonl")
+}
+
+func (s *Store) indexPath() string {
+	return filepath.Join(s.dir, "index.json")
+}
+
+// Store a finished run. One write with O_APPEND, so runs stored at the same time from
+// different processes don't interleave. A run already in the store, e.g. a finished run
+// in an --out directory run again, isn't added twice: the entry stored first is returned.
+func (s *Store) Append(m Manifest, sum Summary) (Entry,error) {
+	if e,ok,err := s.find(m); err != nil || ok {
+		return e,err
+	}
+	e := Entry{
+		ID: fmt.Sprintf("%v-%04x", m.Start.Format("20060102-150405"), rand.Intn(1 << 16)),
+		Manifest: m,
+		Summary: sum,
+	}
+	line,err := json.Marshal(e)
+	if err != nil {
+		return e,err
+	}
+	f,err := os.OpenFile(s.logPath(), os.O_WRONLY | os.O_CREATE | os.O_APPEND, 0o644)
+	if err != nil {
+		return e,err
+	}
+	if _,err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return e,err
+	}
+	if err := f.Close(); err != nil {
+		return e,err
+	}
+	_,err = s.index()
+	return e,err
+}
+
+// The stored entry for run `m`, if any. A run keeps its Start when it is resumed, so
+// that and what decides its results identify it.
+func (s *Store) find(m Manifest) (Entry,bool,error) {
+	f := Filter{Command: m.Command, Strategy: m.Strategy, Rules: m.Rules, Since: m.Start, Until: m.Start.Add(time.Nanosecond)}
+	entries,err := s.Query(f)
+	if err != nil {
+		return Entry{},false,err
+	}
+	for _,e := range entries {
+		o := e.Manifest
+		if o.Start.Equal(m.Start) && o.FirstSeed == m.FirstSeed && o.Games == m.Games &&
+			o.Revision == m.Revision && o.Modified == m.Modified && maps.Equal(o.Params, m.Params) {
+			return e,true,nil
+		}
+	}
+	return Entry{},false,nil
+}
+
+// Stored runs matching `f`, oldest first
+func (s *Store) Query(f Filter) ([]Entry,error) {
+	idx,err := s.index()
+	if err != nil {
+		return nil,err
+	}
+	entries := []Entry{} // Not nil, so that no runs is [] in JSON
+	log,err := os.Open(s.logPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return entries,nil
+	}
+	if err != nil {
+		return nil,err
+	}
+	defer log.Close()
+
+	for _,ie := range idx.Entries {
+		if !f.matchIndex(ie) {
+			continue
+		}
+		e,err := readEntry(log, ie.Offset)
+		if err != nil {
+			return nil,err
+		}
+		if f.matchParams(e.Manifest.Params) {
+			entries = append(entries, e)
+		}
+	}
+	return entries,nil
+}
+
+func (f Filter) matchIndex(ie indexEntry) bool {
+	return (f.Command == "" || f.Command == ie.Command) &&
+		(f.Strategy == "" || f.Strategy == ie.Strategy) &&
+		(f.Rules == "" || f.Rules == ie.Rules) &&
+		(f.Since.IsZero() || !ie.Start.Before(f.Since)) &&
+		(f.Until.IsZero() || ie.Start.Before(f.Until))
+}
+
+func (f Filter) matchParams(params map[string]string) bool {
+	for k,v := range f.Params {
+		if params[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func readEntry(log *os.File, offset int64) (Entry,error) {
+	var e Entry
+	line,err := bufio.NewReader(io.NewSectionReader(log, offset, 1 << 30)).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return e,err
+	}
+	if err := json.Unmarshal(line, &e); err != nil {
+		return e,fmt.Errorf("%v at byte %v: %w", log.Name(), offset, err)
+	}
+	return e,nil
+}
+
+// The index, brought up to date with the log and saved if anything was added
+func (s *Store) index() (index,error) {
+	var idx index
+	if data,err := os.ReadFile(s.indexPath()); err == nil {
+		if json.Unmarshal(data, &idx) != nil {
+			idx = index{} // Rebuild a broken index
+		}
+	}
+
+	log,err := os.Open(s.logPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return index{},nil
+	}
+	if err != nil {
+		return idx,err
+	}
+	defer log.Close()
+	info,err := log.Stat()
+	if err != nil {
+		return idx,err
+	}
+	if info.Size() < idx.LogSize {
+		idx = index{} // The log was replaced
+	}
+	if info.Size() == idx.LogSize {
+		return idx,nil
+	}
+
+	if _,err := log.Seek(idx.LogSize, io.SeekStart); err != nil {
+		return idx,err
+	}
+	r := bufio.NewReader(log)
+	offset := idx.LogSize
+	for {
+		line,err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break // A partial line is still being written; leave it for next time
+		}
+		if err != nil {
+			return idx,err
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return idx,fmt.Errorf("%v at byte %v: %w", s.logPath(), offset, err)
+		}
+		idx.Entries = append(idx.Entries, indexEntry{
+			ID: e.ID,
+			Command: e.Manifest.Command,
+			Strategy: e.Manifest.Strategy,
+			Rules: e.Manifest.Rules,
+			Start: e.Manifest.Start,
+			Offset: offset,
+		})
+		offset += int64(len(line))
+	}
+	idx.LogSize = offset
+	return idx,writeJSON(s.indexPath(), idx)
+}
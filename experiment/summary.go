@@ -0,0 +1,90 @@
+package experiment
+
+import "math"
+
+// Totals over a run's games, kept up to date as rows are added. Written to summary.json.
+// Strategies that win as often can still differ in how far they get in the games they
+// lose, and how many moves they waste on the way.
+type Summary struct {
+	Games int `json:"games"`
+	Wins int `json:"wins"`
+	WinRate float64 `json:"win_rate"`
+	CILow float64 `json:"ci_low"` // 95% confidence interval for the win rate
+	CIHigh float64 `json:"ci_high"`
+	MeanMoves float64 `json:"mean_moves"`
+	MeanByKind map[string]float64 `json:"mean_by_kind"`
+	MeanPasses float64 `json:"mean_passes"`
+	MeanFoundation float64 `json:"mean_foundation"`
+	MeanRevealed float64 `json:"mean_revealed"`
+	MeanNoProgress float64 `json:"mean_no_progress"`
+	MeanStalledAt float64 `json:"mean_stalled_at"`
+	LostMeanFoundation float64 `json:"lost_mean_foundation"` // Over lost games only
+	LostMeanRevealed float64 `json:"lost_mean_revealed"`
+	Ends map[string]int `json:"ends"` // Games by how they ended
+	Seconds float64 `json:"seconds"` // Wall clock, for the whole run
+
+	TotalMoves int `json:"total_moves"`
+	TotalByKind map[string]int `json:"total_by_kind"`
+	TotalPasses int `json:"total_passes"`
+	TotalFoundation int `json:"total_foundation"`
+	TotalRevealed int `json:"total_revealed"`
+	TotalNoProgress int `json:"total_no_progress"`
+	TotalStalledAt int `json:"total_stalled_at"`
+	LostFoundation int `json:"lost_foundation"`
+	LostRevealed int `json:"lost_revealed"`
+}
+
+func (s *Summary) Add(row Row) {
+	if s.Ends == nil {
+		s.Ends = make(map[string]int)
+		s.TotalByKind = make(map[string]int)
+		s.MeanByKind = make(map[string]float64)
+	}
+	s.Games++
+	if row.Won {
+		s.Wins++
+	} else {
+		s.LostFoundation += row.Foundation
+		s.LostRevealed += row.Revealed
+	}
+	s.Ends[row.End.String()]++
+	s.TotalMoves += row.Moves
+	for kind,n := range row.ByKind {
+		s.TotalByKind[kind] += n
+	}
+	s.TotalPasses += row.Passes
+	s.TotalFoundation += row.Foundation
+	s.TotalRevealed += row.Revealed
+	s.TotalNoProgress += row.NoProgress
+	s.TotalStalledAt += row.StalledAt
+
+	n := float64(s.Games)
+	s.WinRate = float64(s.Wins) / n
+	s.CILow,s.CIHigh = Wilson(s.Wins, s.Games, 1.96)
+	s.MeanMoves = float64(s.TotalMoves) / n
+	for kind,total := range s.TotalByKind {
+		s.MeanByKind[kind] = float64(total) / n
+	}
+	s.MeanPasses = float64(s.TotalPasses) / n
+	s.MeanFoundation = float64(s.TotalFoundation) / n
+	s.MeanRevealed = float64(s.TotalRevealed) / n
+	s.MeanNoProgress = float64(s.TotalNoProgress) / n
+	s.MeanStalledAt = float64(s.TotalStalledAt) / n
+	if lost := s.Games - s.Wins; lost > 0 {
+		s.LostMeanFoundation = float64(s.LostFoundation) / float64(lost)
+		s.LostMeanRevealed = float64(s.LostRevealed) / float64(lost)
+	}
+}
+
+// Wilson score interval for a proportion of wins out of n, `z` standard deviations wide.
+// Unlike p ± z*stderr it stays inside [0, 1] and makes sense for 0 or n wins.
+func Wilson(wins, n int, z float64) (lo, hi float64) {
+	if n == 0 {
+		return 0,1
+	}
+	p := float64(wins) / float64(n)
+	nf := float64(n)
+	center := (p + z*z/(2*nf)) / (1 + z*z/nf)
+	half := z / (1 + z*z/nf) * math.Sqrt(p*(1-p)/nf + z*z/(4*nf*nf))
+	return max(center - half, 0),min(center + half, 1)
+}
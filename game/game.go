@@ -11,16 +11,31 @@ const NStacks = 7
 const NFLIP = 3
 // const stackCap = deck.SuitSize + NStacks - 1
 
+// Rule variations
+type Rules struct {
+	NFlip int // Cards turned over from the Deck at a time
+	MaxPasses int // Passes through the Deck allowed, 0 for no limit
+}
+
+// Draw 3, as many passes as you like
+var DefaultRules = Rules{NFlip: NFLIP}
+
 type Game struct {
+	Rules Rules
 	SuitStacks [nSuits]int
 	HiddenStacks [NStacks][]deck.Card // End of slice is top of stack
 	VisibleQueues [NStacks][]deck.Card // End of slice is front of queue (i.e. bottom of "stack")
 	Deck []deck.Card
 	Avail []deck.Card
+	Passes int // Number of times the Avail has been turned back over into the Deck
 }
 
 func NewGame(d deck.Deck) *Game {
-	game := Game{}
+	return NewGameWithRules(d, DefaultRules)
+}
+
+func NewGameWithRules(d deck.Deck, rules Rules) *Game {
+	game := Game{Rules: rules}
 
 	cardIdx := 0
 
@@ -52,6 +67,30 @@ func NewGame(d deck.Deck) *Game {
 	return &game
 }
 
+// Deep copy, so moves can be tried out without touching the original. All the cards go
+// in one allocation, since searches clone a lot. Each slice is capped at its length, so
+// appending to one reallocates instead of running into the next.
+func (game *Game) Clone() *Game {
+	clone := *game
+	n := len(game.Deck) + len(game.Avail)
+	for i := range NStacks {
+		n += len(game.HiddenStacks[i]) + len(game.VisibleQueues[i])
+	}
+	buf := make([]deck.Card, 0, n)
+	take := func(cards []deck.Card) []deck.Card {
+		start := len(buf)
+		buf = append(buf, cards...)
+		return buf[start:len(buf):len(buf)]
+	}
+	for i := range NStacks {
+		clone.HiddenStacks[i] = take(game.HiddenStacks[i])
+		clone.VisibleQueues[i] = take(game.VisibleQueues[i])
+	}
+	clone.Deck = take(game.Deck)
+	clone.Avail = take(game.Avail)
+	return &clone
+}
+
 func (game *Game) Display(hidden bool) {
 	// Suit stacks
 	suitStackString := "  "
@@ -134,15 +173,26 @@ func (game *Game) Display(hidden bool) {
 	fmt.Printf("Deck: %v]\n", deckString)
 }
 
+// Whether the Avail may be turned back over once the Deck runs out
+func (game *Game) CanRedeal() bool {
+	return game.Rules.MaxPasses == 0 || game.Passes + 1 < game.Rules.MaxPasses
+}
+
 func (game *Game) Flip() {
-	// Flip 3 cards from the game.Deck to the game.Avail
+	// Flip NFlip cards from the game.Deck to the game.Avail
 	if len(game.Deck) == 0 {
+		if !game.CanRedeal() {
+			return // Out of passes, nothing happens
+		}
 		// Deck is out, swap Deck and Avail
+		if len(game.Avail) > 0 {
+			game.Passes++
+		}
 		game.Deck = game.Avail
 		game.Avail = make([]deck.Card, 0, len(game.Deck))
 	}
 
-	l := min(len(game.Deck), NFLIP)
+	l := min(len(game.Deck), game.Rules.NFlip)
 	game.Avail = append(game.Avail, game.Deck[:l]...)
 	game.Deck = game.Deck[l:]
 }
@@ -0,0 +1,42 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Rules as written on the command line: "draw3" or "draw1", optionally followed by
+// ":passes=N" to limit the passes through the Deck. Rules.String gives the same form.
+func ParseRules(s string) (Rules,error) {
+	name,opts,_ := strings.Cut(s, ":")
+	var rules Rules
+	switch name {
+	case "draw1":
+		rules.NFlip = 1
+	case "draw3":
+		rules.NFlip = 3
+	default:
+		return rules,fmt.Errorf("unknown rules %q, expected draw1 or draw3, e.g. draw3:passes=3", s)
+	}
+	if opts == "" {
+		return rules,nil
+	}
+	for _,opt := range strings.Split(opts, ",") {
+		key,value,_ := strings.Cut(opt, "=")
+		n,err := strconv.Atoi(value)
+		if key != "passes" || err != nil || n < 0 {
+			return rules,fmt.Errorf("bad rules option %q in %q, expected passes=N", opt, s)
+		}
+		rules.MaxPasses = n
+	}
+	return rules,nil
+}
+
+func (rules Rules) String() string {
+	s := fmt.Sprintf("draw%v", rules.NFlip)
+	if rules.MaxPasses > 0 {
+		s += fmt.Sprintf(":passes=%v", rules.MaxPasses)
+	}
+	return s
+}
@@ -0,0 +1,60 @@
+package game
+
+import "solitaire/deck"
+
+// The game as the player sees it: everything except the face-down tableau cards and,
+// until it has been through once, the order of the Deck. Cards in the Avail have all
+// been face-up at some point, so they count as seen even under the top card.
+type View struct {
+	SuitStacks [nSuits]int
+	VisibleQueues [NStacks][]deck.Card // Same order as Game.VisibleQueues
+	NHidden [NStacks]int
+	Avail []deck.Card
+	Deck []deck.Card // nil until the first pass is complete
+	NDeck int
+	Passes int
+}
+
+// Slices are copied, so the view stays valid as the game goes on
+func (game *Game) View() View {
+	var view View
+	game.ViewInto(&view)
+	return view
+}
+
+// Like View, but reusing the slices already in `view`, which are overwritten
+func (game *Game) ViewInto(view *View) {
+	view.SuitStacks = game.SuitStacks
+	view.Avail = append(view.Avail[:0], game.Avail...)
+	view.NDeck = len(game.Deck)
+	view.Passes = game.Passes
+	for i := range NStacks {
+		view.VisibleQueues[i] = append(view.VisibleQueues[i][:0], game.VisibleQueues[i]...)
+		view.NHidden[i] = len(game.HiddenStacks[i])
+	}
+	if game.DeckSeen() {
+		view.Deck = append(view.Deck[:0], game.Deck...)
+	} else {
+		view.Deck = nil
+	}
+}
+
+// Every card in the Deck has been flipped at least once. Cards only ever leave the Deck
+// by being flipped, so this is true exactly once the Avail has been turned over.
+func (game *Game) DeckSeen() bool {
+	return game.Passes > 0
+}
+
+func (view View) NFoundation() (n int) {
+	for _,size := range view.SuitStacks {
+		n += size
+	}
+	return
+}
+
+func (view View) NHiddenTotal() (n int) {
+	for _,h := range view.NHidden {
+		n += h
+	}
+	return
+}
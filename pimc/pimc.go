@@ -0,0 +1,103 @@
+package pimc
+
+import (
+	"math/rand"
+	"sync/atomic"
+
+	"solitaire/agent"
+	"solitaire/belief"
+	"solitaire/game"
+	"solitaire/solver"
+)
+
+// Perfect information Monte Carlo: deal the cards the player hasn't seen out in a few
+// ways that agree with everything seen, solve each world as if every card were known,
+// and play the move most of the winning lines start with. It assumes it will know
+// everything from the next move on, so it overrates moves that only pay off in some of
+// the worlds, but it needs no eval.
+//
+// Like ExpectimaxStrategy it never plays FromTop: different worlds can disagree about
+// whether a card should be on the foundation, and the agent would move it up and down.
+//
+// Each world is searched for at most MaxNodes positions. When none is won in time, or
+// the winning lines start with moves the agent can't make (part of a stack), Fallback
+// plays instead, without FromTop too.
+type Strategy struct {
+	Samples int
+	MaxNodes int
+	Fallback agent.Strategy // Flips if nil
+	Stats *Stats // Counted into, if not nil
+}
+
+// How often the search was over budget. Safe to share between games played in parallel.
+type Stats struct {
+	Moves atomic.Int64 // Chosen by the search, not counting forced flips
+	Fallbacks atomic.Int64 // Of those, how many the search gave no vote for, so Fallback chose
+	Unknown atomic.Int64 // Worlds the solver gave up on
+	Worlds atomic.Int64
+}
+
+var Default = Strategy{Samples: 8, MaxNodes: 5000}
+
+// As an agent.Strategy, abstaining to Fallback
+func (strat Strategy) Strategy() agent.Strategy {
+	search := agent.StrategyFunc(strat.choose)
+	if strat.Fallback == nil {
+		return search
+	}
+	return agent.Chain{search, agent.NoFromTop{Strategy: strat.Fallback}}
+}
+
+func (strat Strategy) choose(g *game.Game, moves *agent.Moves, rng *rand.Rand) int {
+	if moves.Len() == 0 {
+		return -1 // Nothing to choose between
+	}
+	b := belief.New(g.View())
+	votes := make(map[int]int)
+	best, unknown := agent.Abstain, 0
+	for range max(strat.Samples, 1) {
+		r := solver.Solve(b.Sample(rng, g.Rules), strat.MaxNodes)
+		if r.Status == solver.Unknown {
+			unknown++
+		}
+		if r.Status != solver.Won || len(r.Moves) == 0 {
+			continue
+		}
+		idx := agentMove(g, moves, r.Moves[0])
+		if idx == agent.Abstain {
+			continue
+		}
+		votes[idx]++
+		if best == agent.Abstain || votes[idx] > votes[best] {
+			best = idx
+		}
+	}
+	if s := strat.Stats; s != nil {
+		s.Moves.Add(1)
+		s.Worlds.Add(int64(max(strat.Samples, 1)))
+		s.Unknown.Add(int64(unknown))
+		if best == agent.Abstain {
+			s.Fallbacks.Add(1)
+		}
+	}
+	return best
+}
+
+// The index in `moves` of the solver's move `m`, or Abstain if the agent can't play it.
+// Moves that flip first start with a flip.
+func agentMove(g *game.Game, moves *agent.Moves, m solver.Move) int {
+	if m.Flips > 0 || m.Kind == agent.Flip {
+		return -1
+	}
+	if m.Kind == agent.Tableau && m.N != len(g.VisibleQueues[m.Src]) {
+		return agent.Abstain // Agents only move whole queues
+	}
+	if m.Kind == agent.FromTop {
+		return agent.Abstain
+	}
+	idx := moves.IndexOf(agent.Move{Kind: m.Kind, Src: m.Src, Dst: m.Dst})
+	if idx == -2 {
+		return agent.Abstain
+	}
+	return idx
+}
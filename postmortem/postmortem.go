@@ -0,0 +1,113 @@
+package postmortem
+
+import (
+	"solitaire/deck"
+	"solitaire/game"
+)
+
+// Why games were lost, from where they ended. Built up one lost game at a time with Add.
+//
+// Positions in the tableau are by where cards were dealt: column, and depth counted from
+// the top of the column at the deal, so the card just under the face-up one is at depth 1.
+// Face-down cards never move, so a card still face down at the end is where it was dealt.
+type Report struct {
+	Games int `json:"games"`
+	MeanHidden float64 `json:"mean_hidden"` // Cards still face down at the end
+	// Games in which the card dealt at [column][depth] was still face down
+	HiddenAt [game.NStacks][game.NStacks]int `json:"hidden_at"`
+	// Games each card was still face down in, by card (e.g. "A♤")
+	HiddenCards map[string]int `json:"hidden_cards"`
+
+	// The next card each foundation needed, and where it was at the end: face down, under
+	// other cards in a column, in the stock (Deck or Avail), or free to play
+	Blockers map[string]int `json:"blockers"`
+	HiddenBlockersByRank [deck.SuitSize]int `json:"hidden_blockers_by_rank"`
+	HiddenBlockersBySuit [deck.NSuits]int `json:"hidden_blockers_by_suit"`
+	HiddenBlockersAt [game.NStacks][game.NStacks]int `json:"hidden_blockers_at"`
+
+	BuriedAce int `json:"buried_ace"` // Games with an ace still face down
+	// Games with a king on top of face-down cards and no empty column to move it to
+	KingNeedsColumn int `json:"king_needs_column"`
+
+	totalHidden int
+}
+
+const (
+	Hidden = "hidden"
+	Covered = "covered"
+	InStock = "stock"
+	Free = "free"
+)
+
+func New() *Report {
+	return &Report{HiddenCards: make(map[string]int), Blockers: make(map[string]int)}
+}
+
+// Add a lost game, as it ended
+func (r *Report) Add(g *game.Game) {
+	r.Games++
+	buriedAce := false
+	for col,stack := range g.HiddenStacks {
+		r.totalHidden += len(stack)
+		for i,c := range stack {
+			r.HiddenAt[col][col - i]++
+			r.HiddenCards[c.String()]++
+			buriedAce = buriedAce || c.Rank == deck.Ace
+		}
+	}
+	r.MeanHidden = float64(r.totalHidden) / float64(r.Games)
+	if buriedAce {
+		r.BuriedAce++
+	}
+
+	emptyColumn, kingBlocks := false, false
+	for col,queue := range g.VisibleQueues {
+		if len(queue) == 0 {
+			emptyColumn = true
+		} else if queue[len(queue) - 1].Rank == deck.King && len(g.HiddenStacks[col]) > 0 {
+			kingBlocks = true
+		}
+	}
+	if kingBlocks && !emptyColumn {
+		r.KingNeedsColumn++
+	}
+
+	for suit,size := range g.SuitStacks {
+		if size == deck.SuitSize {
+			continue
+		}
+		c := deck.NewCard(size, suit)
+		where, col, depth := locate(g, c)
+		r.Blockers[where]++
+		if where == Hidden {
+			r.HiddenBlockersByRank[c.Rank]++
+			r.HiddenBlockersBySuit[c.Suit]++
+			r.HiddenBlockersAt[col][depth]++
+		}
+	}
+}
+
+// Where `c` is, and if face down, its column and depth
+func locate(g *game.Game, c deck.Card) (where string, col, depth int) {
+	for col,stack := range g.HiddenStacks {
+		for i,h := range stack {
+			if h == c {
+				return Hidden,col,col - i
+			}
+		}
+	}
+	for col,queue := range g.VisibleQueues {
+		for i,v := range queue {
+			if v == c {
+				if i == 0 {
+					return Free,col,0
+				}
+				return Covered,col,0
+			}
+		}
+	}
+	if len(g.Avail) > 0 && g.Avail[len(g.Avail) - 1] == c {
+		return Free,-1,0
+	}
+	return InStock,-1,0
+}
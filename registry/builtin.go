@@ -0,0 +1,251 @@
+package registry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"solitaire/agent"
+	"solitaire/dsl"
+	"solitaire/eval"
+	"solitaire/pimc"
+)
+
+func ftoa(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// An evaluator preset or config path, or ProgressEval for ""
+func evalParam(p *Params, name string) agent.EvalFunc {
+	path := p.String(name)
+	if path == "" {
+		return nil
+	}
+	e,err := eval.Open(path)
+	if err != nil {
+		p.fail(name, err)
+		return nil
+	}
+	return e.Func()
+}
+
+// A strategy by name, with its defaults. Specs can't nest, since they use commas themselves.
+func strategyParam(p *Params, name string, value string) agent.Strategy {
+	strat,err := New(value, nil)
+	if err != nil {
+		p.fail(name, err)
+	}
+	return strat
+}
+
+// Strategies by name, as a+b+c
+func strategiesParam(p *Params, name string) []agent.Strategy {
+	var strats []agent.Strategy
+	for _,s := range strings.Split(p.Required(name), "+") {
+		strats = append(strats, strategyParam(p, name, s))
+	}
+	return strats
+}
+
+func init() {
+	Register(Entry{
+		Name: "manual",
+		Doc: "you play, from the terminal",
+		New: func(p *Params) (agent.Strategy,error) { return agent.Manual{}, nil },
+	})
+
+	Register(Entry{
+		Name: "random",
+		Doc: "any legal move, uniformly",
+		New: func(p *Params) (agent.Strategy,error) { return agent.RandomStrategy{}, nil },
+	})
+
+	// Defaults are the third agent from notes.md
+	Register(Entry{
+		Name: "probabilistic",
+		Doc: "a random kind of move, with fixed relative probabilities for each kind",
+		Params: []Param{
+			{"PFlip", "0.000001", "weight of flipping"},
+			{"PTableau", "10000", "weight of tableau moves"},
+			{"PAvail", "1", "weight of moves from the Avail"},
+			{"PToTop", "1", "weight of moves to the foundation"},
+			{"PFromTop", "0", "weight of moves off the foundation"},
+		},
+		New: func(p *Params) (agent.Strategy,error) {
+			strat := agent.ProbabilisticStrategy{
+				PFlip: float32(p.Float("PFlip")),
+				PTableau: float32(p.Float("PTableau")),
+				PAvail: float32(p.Float("PAvail")),
+				PToTop: float32(p.Float("PToTop")),
+				PFromTop: float32(p.Float("PFromTop")),
+			}
+			return strat,p.Err()
+		},
+		Tune: agent.ProbabilisticStrategy{}.Params(),
+	})
+
+	def := agent.DefaultStockStrategy
+	Register(Entry{
+		Name: "stock",
+		Doc: "hand-written scores, planning for the waste cards it can reach",
+		Params: []Param{
+			{"PlanWeight", ftoa(def.PlanWeight), "score for each reachable waste card a move gives a home"},
+			{"NextPassWeight", ftoa(def.NextPassWeight), "how much cards only reachable next pass count"},
+		},
+		New: func(p *Params) (agent.Strategy,error) {
+			strat := agent.StockStrategy{PlanWeight: p.Float("PlanWeight"), NextPassWeight: p.Float("NextPassWeight")}
+			return strat,p.Err()
+		},
+		Tune: []agent.Param{
+			{Name: "PlanWeight", Min: 0.1, Max: 1000, Log: true},
+			{Name: "NextPassWeight", Min: 0, Max: 1},
+		},
+	})
+
+	Register(Entry{
+		Name: "td",
+		Doc: "one-move greedy on a learned value function (see cmd/tdtrain)",
+		Params: []Param{
+			{"Weights", "td_weights.json", "value function weights"},
+			{"Epsilon", "0", "probability of a random move"},
+		},
+		New: func(p *Params) (agent.Strategy,error) {
+			epsilon := p.Float("Epsilon")
+			path := p.Required("Weights")
+			if p.Err() != nil {
+				return nil,p.Err()
+			}
+			net,err := agent.LoadValueNet(path)
+			if err != nil {
+				return nil,err
+			}
+			return agent.TDStrategy{Net: net, Epsilon: epsilon}, nil
+		},
+	})
+
+	exp := agent.DefaultExpectimaxStrategy
+	Register(Entry{
+		Name: "expectimax",
+		Doc: "searches a few moves ahead, averaging over the cards that could turn up",
+		Params: []Param{
+			{"Depth", strconv.Itoa(exp.Depth), "moves to look ahead"},
+			{"MaxOutcomes", strconv.Itoa(exp.MaxOutcomes), "outcomes tried at each card turned up"},
+			{"Eval", "", "evaluator preset ("+strings.Join(eval.Presets(), ", ")+") or config file (see package eval), foundation - hidden if not given"},
+		},
+		New: func(p *Params) (agent.Strategy,error) {
+			strat := agent.ExpectimaxStrategy{Depth: p.Int("Depth"), MaxOutcomes: p.Int("MaxOutcomes"), Eval: evalParam(p, "Eval")}
+			return strat,p.Err()
+		},
+	})
+
+	beam := agent.DefaultBeamStrategy
+	Register(Entry{
+		Name: "beam",
+		Doc: "beam search over moves with known outcomes",
+		Params: []Param{
+			{"Width", strconv.Itoa(beam.Width), "positions kept after each move"},
+			{"Depth", strconv.Itoa(beam.Depth), "moves to look ahead"},
+			{"Eval", "", "evaluator preset ("+strings.Join(eval.Presets(), ", ")+") or config file (see package eval), foundation - hidden if not given"},
+		},
+		New: func(p *Params) (agent.Strategy,error) {
+			strat := agent.BeamStrategy{Width: p.Int("Width"), Depth: p.Int("Depth"), Eval: evalParam(p, "Eval")}
+			return strat,p.Err()
+		},
+	})
+
+	pim := pimc.Default
+	Register(Entry{
+		Name: "pimc",
+		Doc: "solves a few deals of the unseen cards and plays the move most winning lines start with",
+		Params: []Param{
+			{"Samples", strconv.Itoa(pim.Samples), "deals of the unseen cards to solve each move"},
+			{"MaxNodes", strconv.Itoa(pim.MaxNodes), "positions the solver searches in each"},
+			{"Fallback", "stock", "strategy (by name, with its defaults) for when no deal is won in time"},
+		},
+		New: func(p *Params) (agent.Strategy,error) {
+			strat := pimc.Strategy{Samples: p.Int("Samples"), MaxNodes: p.Int("MaxNodes")}
+			if name := p.String("Fallback"); name != "" {
+				fallback,err := New(name, nil)
+				if err != nil {
+					p.fail("Fallback", err)
+				}
+				strat.Fallback = fallback
+			}
+			return strat.Strategy(),p.Err()
+		},
+	})
+
+	Register(Entry{
+		Name: "chain",
+		Doc: "asks each strategy in turn and plays the first move one doesn't abstain on",
+		Params: []Param{
+			{"Strategies", "", "strategy names, with their defaults, in order, as a+b"},
+		},
+		New: func(p *Params) (agent.Strategy,error) {
+			return agent.Chain(strategiesParam(p, "Strategies")),p.Err()
+		},
+	})
+
+	Register(Entry{
+		Name: "vote",
+		Doc: "plays the move with the most (weighted) votes from the strategies",
+		Params: []Param{
+			{"Strategies", "", "strategy names, with their defaults, as a+b"},
+			{"Weights", "", "their weights as 1+0.5, 1 for any left out"},
+		},
+		New: func(p *Params) (agent.Strategy,error) {
+			vote := agent.Vote{Strategies: strategiesParam(p, "Strategies")}
+			if w := p.String("Weights"); w != "" {
+				for _,s := range strings.Split(w, "+") {
+					weight,err := strconv.ParseFloat(s, 64)
+					if err != nil {
+						p.bad("Weights", err)
+					}
+					vote.Weights = append(vote.Weights, weight)
+				}
+			}
+			if len(vote.Weights) > len(vote.Strategies) && p.Err() == nil {
+				p.bad("Weights", fmt.Errorf("%v weights for %v strategies", len(vote.Weights), len(vote.Strategies)))
+			}
+			return vote,p.Err()
+		},
+	})
+
+	Register(Entry{
+		Name: "epsilon",
+		Doc: "plays Strategy, except with probability Epsilon it plays Random",
+		Params: []Param{
+			{"Strategy", "", "strategy name, with its defaults"},
+			{"Epsilon", "0.1", "chance of playing Random instead"},
+			{"Random", "random", "strategy name, with its defaults"},
+		},
+		New: func(p *Params) (agent.Strategy,error) {
+			mix := agent.EpsilonMix{
+				Strategy: strategyParam(p, "Strategy", p.Required("Strategy")),
+				Random: strategyParam(p, "Random", p.Required("Random")),
+				Epsilon: p.Float("Epsilon"),
+			}
+			return mix,p.Err()
+		},
+		Tune: []agent.Param{{Name: "Epsilon", Min: 0, Max: 1}},
+	})
+
+	Register(Entry{
+		Name: "rules",
+		Doc: "a strategy from a rules file (see package dsl)",
+		Params: []Param{
+			{"File", "", "the rules file"},
+		},
+		New: func(p *Params) (agent.Strategy,error) {
+			path := p.Required("File")
+			if p.Err() != nil {
+				return nil,p.Err()
+			}
+			prog,err := dsl.Load(path)
+			if err != nil {
+				return nil,err
+			}
+			return prog.Strategy(),nil
+		},
+	})
+}
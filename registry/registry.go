@@ -0,0 +1,206 @@
+package registry
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"solitaire/agent"
+)
+
+// Strategies by name, so that commands can build one from flags, e.g.
+//
+//	solitaire sim --strategy=probabilistic --param PTableau=10000
+//
+// Parameters are strings on the command line, so they are strings here too, and each
+// constructor parses its own (see Params).
+type Entry struct {
+	Name string
+	Doc string
+	Params []Param
+	New func(p *Params) (agent.Strategy,error)
+	Tune []agent.Param // Numeric parameters an optimizer can search, and over what (see Tunable)
+}
+
+type Param struct {
+	Name string
+	Default string // "" for parameters that have to be given
+	Doc string
+}
+
+var entries = map[string]Entry{}
+
+// Add a strategy. Panics if the name is taken, since that's a bug.
+func Register(e Entry) {
+	if _,ok := entries[e.Name]; ok {
+		panic(fmt.Sprintf("strategy %q registered twice", e.Name))
+	}
+	entries[e.Name] = e
+}
+
+func Lookup(name string) (Entry,bool) {
+	e,ok := entries[name]
+	return e,ok
+}
+
+// Every registered strategy, by name
+func All() []Entry {
+	all := make([]Entry, 0, len(entries))
+	for _,e := range entries {
+		all = append(all, e)
+	}
+	slices.SortFunc(all, func(a, b Entry) int { return strings.Compare(a.Name, b.Name) })
+	return all
+}
+
+func Names() []string {
+	var names []string
+	for _,e := range All() {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+// Build strategy `name`, with the parameters in `values` and defaults for the rest
+func New(name string, values map[string]string) (agent.Strategy,error) {
+	all,err := Resolve(name, values)
+	if err != nil {
+		return nil,err
+	}
+	return entries[name].New(&Params{strategy: name, values: all})
+}
+
+// A strategy or parameter given wrongly: an unknown name, or a value that doesn't parse.
+// Other errors from New are from building the strategy, e.g. a weights file that won't load.
+type ParamError struct {
+	Err error
+}
+
+func (e ParamError) Error() string {
+	return e.Err.Error()
+}
+
+func (e ParamError) Unwrap() error {
+	return e.Err
+}
+
+// Every parameter of strategy `name`: `values`, with defaults for the rest. What to
+// write down to be able to build the same strategy again after the defaults change.
+func Resolve(name string, values map[string]string) (map[string]string,error) {
+	e,ok := entries[name]
+	if !ok {
+		return nil,ParamError{fmt.Errorf("unknown strategy %q, expected one of %v", name, Names())}
+	}
+	all := map[string]string{}
+	for _,param := range e.Params {
+		all[param.Name] = param.Default
+	}
+	for k,v := range values {
+		if _,ok := all[k]; !ok {
+			var names []string
+			for _,param := range e.Params {
+				names = append(names, param.Name)
+			}
+			return nil,ParamError{fmt.Errorf("strategy %v has no parameter %q, expected one of %v", name, k, names)}
+		}
+		all[k] = v
+	}
+	return all,nil
+}
+
+// --param, which can be given more than once (a flag.Value)
+type ParamFlag []string
+
+func (p *ParamFlag) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *ParamFlag) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
+
+// Parse NAME=VALUE pairs, as given to --param
+func ParseParams(pairs []string) (map[string]string,error) {
+	values := make(map[string]string, len(pairs))
+	for _,pair := range pairs {
+		k,v,ok := strings.Cut(pair, "=")
+		if !ok || k == "" {
+			return nil,ParamError{fmt.Errorf("bad parameter %q, expected NAME=VALUE", pair)}
+		}
+		values[k] = v
+	}
+	return values,nil
+}
+
+// A strategy and its parameters in one string, as NAME or NAME:K=V,K=V, e.g.
+// "expectimax:Depth=3,MaxOutcomes=4". For commands that take more than one strategy.
+func ParseSpec(spec string) (name string, values map[string]string, err error) {
+	name,rest,_ := strings.Cut(spec, ":")
+	if rest == "" {
+		return name,nil,nil
+	}
+	values,err = ParseParams(strings.Split(rest, ","))
+	return name,values,err
+}
+
+func NewFromSpec(spec string) (agent.Strategy,error) {
+	name,values,err := ParseSpec(spec)
+	if err != nil {
+		return nil,err
+	}
+	return New(name, values)
+}
+
+// Parameter values for a constructor. The getters remember the first error, so that a
+// constructor can read everything and then return Err.
+type Params struct {
+	strategy string
+	values map[string]string
+	err error
+}
+
+func (p *Params) fail(name string, err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("strategy %v, parameter %v: %w", p.strategy, name, err)
+	}
+}
+
+// Like fail, for a value given wrongly rather than one that couldn't be used
+func (p *Params) bad(name string, err error) {
+	p.fail(name, ParamError{err})
+}
+
+func (p *Params) String(name string) string {
+	return p.values[name]
+}
+
+// Like String, but the parameter has to be given
+func (p *Params) Required(name string) string {
+	v := p.values[name]
+	if v == "" {
+		p.bad(name, fmt.Errorf("required"))
+	}
+	return v
+}
+
+func (p *Params) Float(name string) float64 {
+	v,err := strconv.ParseFloat(p.values[name], 64)
+	if err != nil {
+		p.bad(name, err)
+	}
+	return v
+}
+
+func (p *Params) Int(name string) int {
+	v,err := strconv.Atoi(p.values[name])
+	if err != nil {
+		p.bad(name, err)
+	}
+	return v
+}
+
+func (p *Params) Err() error {
+	return p.err
+}
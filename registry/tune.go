@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"solitaire/agent"
+)
+
+// A registered strategy as an agent.Tunable, searching the parameters in its Entry.Tune.
+// The rest are fixed at `values`, or their defaults.
+func Tunable(name string, values map[string]string) (agent.Tunable,error) {
+	all,err := Resolve(name, values)
+	if err != nil {
+		return nil,err
+	}
+	e := entries[name]
+	if len(e.Tune) == 0 {
+		var names []string
+		for _,e := range All() {
+			if len(e.Tune) > 0 {
+				names = append(names, e.Name)
+			}
+		}
+		return nil,ParamError{fmt.Errorf("strategy %v has no parameters to tune, these do: %v", name, names)}
+	}
+	for _,p := range e.Tune {
+		if _,err := strconv.ParseFloat(all[p.Name], 64); err != nil {
+			return nil,ParamError{fmt.Errorf("strategy %v, parameter %v: %w", name, p.Name, err)}
+		}
+	}
+	strat,err := New(name, all)
+	if err != nil {
+		return nil,err
+	}
+	return tunable{Strategy: strat, name: name, values: all, params: e.Tune},nil
+}
+
+type tunable struct {
+	agent.Strategy
+	name string
+	values map[string]string // Every parameter
+	params []agent.Param
+}
+
+// As a spec with every parameter, e.g. stock:NextPassWeight=0.5,PlanWeight=25
+func (t tunable) String() string {
+	var pairs []string
+	for k,v := range t.values {
+		pairs = append(pairs, k + "=" + v)
+	}
+	slices.Sort(pairs)
+	return t.name + ":" + strings.Join(pairs, ",")
+}
+
+func (t tunable) Params() []agent.Param {
+	return t.params
+}
+
+func (t tunable) Values() []float64 {
+	values := make([]float64, len(t.params))
+	for i,p := range t.params {
+		values[i],_ = strconv.ParseFloat(t.values[p.Name], 64) // Checked by Tunable
+	}
+	return values
+}
+
+func (t tunable) WithValues(values []float64) agent.Tunable {
+	if len(values) != len(t.params) {
+		panic(fmt.Sprintf("strategy %v has %v parameters to tune, got %v values!", t.name, len(t.params), len(values)))
+	}
+	all := make(map[string]string, len(t.values))
+	for k,v := range t.values {
+		all[k] = v
+	}
+	for i,p := range t.params {
+		all[p.Name] = ftoa(values[i])
+	}
+	strat,err := New(t.name, all)
+	if err != nil {
+		panic(fmt.Sprintf("strategy %v with %v: %v", t.name, all, err))
+	}
+	return tunable{Strategy: strat, name: t.name, values: all, params: t.params}
+}
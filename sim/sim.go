@@ -0,0 +1,289 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"solitaire/agent"
+	"solitaire/deck"
+	"solitaire/game"
+)
+
+// Games running longer than this are counted as losses. Strategies that can move cards
+// back off the foundation can otherwise cycle (almost) forever.
+const MaxMoves = 10000
+
+// The deal for a given seed. Uses its own random source, so deals do not depend on
+// anything else drawing from math/rand.
+func Deal(seed int64) deck.Deck {
+	d := deck.NewDeck()
+	d.ShuffleWith(rand.New(rand.NewSource(seed)))
+	return d
+}
+
+// The agent's random source is seeded from the deal seed too, so each game is reproducible.
+func agentSeed(seed int64) int64 {
+	return seed ^ 0x5deece66d
+}
+
+// Called with each move before it is played, see agent.Agent.OnMove
+type Observer func(game *game.Game, moves *agent.Moves, moveID int)
+
+// How a game is played. The zero value plays by game.DefaultRules, quietly.
+type Options struct {
+	Rules game.Rules // DefaultRules if zero
+	Verbose bool // Display the game after every move
+	Observe Observer // Called with every move, if not nil
+}
+
+func (opts Options) rules() game.Rules {
+	if opts.Rules == (game.Rules{}) {
+		return game.DefaultRules
+	}
+	return opts.Rules
+}
+
+// Play the deal for `seed` with `strategy` until the agent stops making progress
+func RunGame(strategy agent.Strategy, seed int64, verbose bool) (won bool) {
+	return Play(strategy, seed, Options{Verbose: verbose}).Won
+}
+
+func RunGameObserved(strategy agent.Strategy, seed int64, verbose bool, observe Observer) (won bool) {
+	return Play(strategy, seed, Options{Verbose: verbose, Observe: observe}).Won
+}
+
+// Why a game ended
+type End byte
+
+const (
+	Won End = iota
+	Stuck // Too many turns in a row without moving a card
+	TooLong // MaxMoves
+)
+
+var endNames = [...]string{Won: "won", Stuck: "stuck", TooLong: "too_long"}
+
+func (end End) String() string {
+	return endNames[end]
+}
+
+func (end End) MarshalText() ([]byte,error) {
+	return []byte(end.String()), nil
+}
+
+func (end *End) UnmarshalText(text []byte) error {
+	for e,name := range endNames {
+		if name == string(text) {
+			*end = End(e)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown end of game %q", text)
+}
+
+// How a game went. Progress means a card turned face up or onto the foundation; other
+// moves, and flips, only shuffle cards around.
+type GameResult struct {
+	Won bool
+	Moves int // Including flips
+	ByKind [agent.NMoveKinds]int // Moves of each agent.MoveKind, flips included
+	Passes int // Times the Avail was turned back over into the Deck
+	Revealed int // Of the cards dealt face down
+	Foundation int // Cards on the foundation at the end
+	FoundationTop [deck.NSuits]int // Highest rank on each suit's foundation, 0 for none
+	NoProgress int // Moves that made no progress
+	StalledAt int // Moves up to the last one that made progress; the rest were wasted
+	End End
+	Duration time.Duration
+	Game *game.Game // Final state
+}
+
+// Cards dealt face down
+const nDealtHidden = game.NStacks * (game.NStacks - 1) / 2
+
+// Cards face up or on the foundation, which only ever goes up (bar moves off the foundation)
+func progress(g *game.Game) int {
+	n := nDealtHidden
+	for i := range game.NStacks {
+		n -= len(g.HiddenStacks[i])
+	}
+	for _,size := range g.SuitStacks {
+		n += size
+	}
+	return n
+}
+
+func Play(strategy agent.Strategy, seed int64, opts Options) GameResult {
+	return PlayDeck(strategy, Deal(seed), seed, opts)
+}
+
+// Play deck `d` instead of the deal for a seed. The agent's random source is still seeded
+// from `seed`.
+func PlayDeck(strategy agent.Strategy, d deck.Deck, seed int64, opts Options) GameResult {
+	start := time.Now()
+	var r GameResult
+	observe := func(g *game.Game, moves *agent.Moves, moveID int) {
+		r.ByKind[moves.At(moveID).Kind]++
+		if opts.Observe != nil {
+			opts.Observe(g, moves, moveID)
+		}
+	}
+	verbose := opts.Verbose
+	game := game.NewGameWithRules(d, opts.rules())
+
+	agent,err := agent.NewAgent(game, strategy)
+	if err != nil {
+		panic(err)
+	}
+	agent.Seed(agentSeed(seed))
+	agent.OnMove(observe)
+
+	if verbose { game.Display(true) }
+
+	var turnsWithoutMove int
+	nMoves := 0
+	best := progress(game)
+	for ; nMoves < MaxMoves && turnsWithoutMove < max(len(game.Avail) + len(game.Deck), 10) && !game.IsWon(); nMoves++ {
+		movedCard := agent.Act(verbose)
+		if verbose { game.Display(true) }
+
+		if movedCard {
+			turnsWithoutMove = 0
+		} else {
+			turnsWithoutMove++
+		}
+		// Against the best so far, so that moving a card off the foundation and back isn't progress
+		if p := progress(game); p > best {
+			best = p
+			r.StalledAt = nMoves + 1
+		} else {
+			r.NoProgress++
+		}
+	}
+
+	if verbose {
+		fmt.Println("Game is over! Final state:")
+		game.Display(false)
+	}
+	r.Won = game.IsWon()
+	r.Moves = nMoves
+	r.Passes = game.Passes
+	r.Revealed = nDealtHidden
+	for i := range game.HiddenStacks {
+		r.Revealed -= len(game.HiddenStacks[i])
+	}
+	r.FoundationTop = game.SuitStacks
+	for _,size := range game.SuitStacks {
+		r.Foundation += size
+	}
+	r.End = Stuck
+	switch {
+	case r.Won:
+		r.End = Won
+	case nMoves >= MaxMoves:
+		r.End = TooLong
+	}
+	r.Game = game
+	r.Duration = time.Since(start)
+	return r
+}
+
+// Play the deal for every seed in `seeds`, spread over `workers` goroutines.
+// Returns the fraction of games won.
+func WinRate(strategy agent.Strategy, seeds []int64, workers int) float64 {
+	return WinRateWith(strategy, seeds, workers, Options{})
+}
+
+func WinRateWith(strategy agent.Strategy, seeds []int64, workers int, opts Options) float64 {
+	if len(seeds) == 0 {
+		return 0
+	}
+	wins := 0
+	for _,r := range PlayAll(strategy, seeds, workers, opts) {
+		if r.Won {
+			wins++
+		}
+	}
+	return float64(wins) / float64(len(seeds))
+}
+
+// Play the deal for every seed in `seeds`, spread over `workers` goroutines. The results
+// are in the same order as the seeds. Observe, if set, has to be safe to call from
+// several goroutines at once.
+func PlayAll(strategy agent.Strategy, seeds []int64, workers int, opts Options) []GameResult {
+	results := make([]GameResult, len(seeds))
+	Stream(strategy, seeds, workers, opts, nil, func(i int, r GameResult) {
+		results[i] = r
+	})
+	return results
+}
+
+// Like PlayAll, but hands each result to `emit` as soon as it and all those before it are
+// done, so always in seed order, and from the calling goroutine. Once `stop` is closed
+// (nil for never) no new games are started, but those already going are finished and
+// emitted. Returns the number of results emitted, which are for the first that many seeds.
+func Stream(strategy agent.Strategy, seeds []int64, workers int, opts Options, stop <-chan struct{}, emit func(i int, r GameResult)) int {
+	return Ordered(len(seeds), workers, stop, func(i int) GameResult {
+		return Play(strategy, seeds[i], opts)
+	}, emit)
+}
+
+// Like Stream, for any work: runs do(0) to do(n-1) on `workers` goroutines, and hands the
+// results to `emit` in order, from the calling goroutine. `do` has to be safe to call from
+// several goroutines at once.
+func Ordered[T any](n, workers int, stop <-chan struct{}, do func(i int) T, emit func(i int, r T)) int {
+	type done struct {
+		i int
+		r T
+	}
+	jobs := make(chan int)
+	results := make(chan done)
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results <- done{i, do(i)}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range n {
+			select {
+			case jobs <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Work finishes out of order; hold on to results until those before them are in
+	pending := make(map[int]T)
+	next := 0
+	for d := range results {
+		pending[d.i] = d.r
+		for r,ok := pending[next]; ok; r,ok = pending[next] {
+			delete(pending, next)
+			emit(next, r)
+			next++
+		}
+	}
+	return next
+}
+
+// Seeds start, start+1, ..., start+n-1
+func SeedRange(start int64, n int) []int64 {
+	seeds := make([]int64, n)
+	for i := range seeds {
+		seeds[i] = start + int64(i)
+	}
+	return seeds
+}
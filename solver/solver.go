@@ -0,0 +1,429 @@
+package solver
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"solitaire/agent"
+	"solitaire/deck"
+	"solitaire/game"
+)
+
+// Solves "thoughtful" solitaire: with every card known, can the game be won from here?
+// Depth-first search with a transposition table, so no position is searched twice.
+// Cards that can never be needed in the tableau again go to the foundation without
+// searching alternatives, and flips only happen as part of playing the card turned up.
+//
+// Moving part of a stack is only tried to get at the card underneath for the foundation.
+// The other uses, shuffling sequences between columns, blow the search up and almost
+// never matter, but it means Unwinnable is nearly, not strictly, a proof.
+type Status byte
+
+const (
+	Unknown Status = iota // Gave up: hit the node limit
+	Won
+	Unwinnable
+)
+
+var statusNames = [...]string{Unknown: "unknown", Won: "won", Unwinnable: "unwinnable"}
+
+func (s Status) String() string {
+	return statusNames[s]
+}
+
+func (s Status) MarshalText() ([]byte,error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Status) UnmarshalText(text []byte) error {
+	for st,name := range statusNames {
+		if name == string(text) {
+			*s = Status(st)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown solver status %q", text)
+}
+
+// A move in the full game. Unlike agent.Move, a Tableau move can take just the front N
+// cards of a stack, and moves from the Avail can flip first. The search never flips
+// except to play the card it turns up, so it doesn't search the positions in between.
+type Move struct {
+	Kind agent.MoveKind `json:"kind"`
+	Src int `json:"src"`
+	Dst int `json:"dst"`
+	N int `json:"n,omitempty"` // Tableau only
+	Flips int `json:"flips,omitempty"` // AvailToTableau and AvailToTop only
+}
+
+func (m Move) String() string {
+	s := agent.Move{Kind: m.Kind, Src: m.Src, Dst: m.Dst}.String()
+	if m.Kind == agent.Tableau {
+		s = fmt.Sprintf("Tableau(%v,%v,%v)", m.Src, m.Dst, m.N)
+	}
+	if m.Flips > 0 {
+		s = fmt.Sprintf("%vxFlip+%v", m.Flips, s)
+	}
+	return s
+}
+
+// An error if a stack or suit `m` names doesn't exist, so moves read from a file or typed
+// in can't crash the game
+func (m Move) check() error {
+	stack := func(what string, i int) error {
+		if i < 0 || i >= game.NStacks {
+			return fmt.Errorf("invalid move %v: no %v stack %v", m, what, i)
+		}
+		return nil
+	}
+	switch m.Kind {
+	case agent.Tableau:
+		if m.N < 0 {
+			return fmt.Errorf("invalid move %v: can't move %v cards", m, m.N)
+		}
+		return errors.Join(stack("source", m.Src), stack("destination", m.Dst))
+	case agent.AvailToTableau:
+		return stack("destination", m.Dst)
+	case agent.ToTop:
+		return stack("source", m.Src)
+	case agent.FromTop:
+		if m.Src < 0 || m.Src >= deck.NSuits {
+			return fmt.Errorf("invalid move %v: no suit %v", m, m.Src)
+		}
+		return stack("destination", m.Dst)
+	}
+	return nil
+}
+
+// Play `m` in `g`
+func Apply(g *game.Game, m Move) error {
+	if err := m.check(); err != nil {
+		return err
+	}
+	for range m.Flips {
+		g.Flip()
+	}
+	switch m.Kind {
+	case agent.Flip:
+		g.Flip()
+		return nil
+	case agent.Tableau:
+		return g.Move(m.Src, m.Dst, m.N)
+	case agent.AvailToTableau:
+		return g.MoveFromAvail(m.Dst)
+	case agent.AvailToTop:
+		return g.MoveAvailToTop()
+	case agent.ToTop:
+		return g.MoveToTop(m.Src)
+	case agent.FromTop:
+		return g.MoveFromTop(m.Src, m.Dst)
+	}
+	return fmt.Errorf("invalid move %v", m)
+}
+
+type Result struct {
+	Status Status
+	Moves []Move // The winning line, if Won
+	Nodes int // Positions searched
+}
+
+// Search from `g` (which isn't changed) for at most maxNodes positions
+func Solve(g *game.Game, maxNodes int) Result {
+	s := search{maxNodes: maxNodes, seen: make(map[uint64]bool)}
+	won := s.dfs(g.Clone())
+	r := Result{Nodes: s.nodes}
+	switch {
+	case won:
+		r.Status = Won
+		// The path was built on the way back up
+		for i, j := 0, len(s.path) - 1; i < j; i, j = i+1, j-1 {
+			s.path[i], s.path[j] = s.path[j], s.path[i]
+		}
+		r.Moves = s.path
+	case s.nodes >= maxNodes:
+		r.Status = Unknown
+	default:
+		r.Status = Unwinnable
+	}
+	return r
+}
+
+type search struct {
+	maxNodes int
+	nodes int
+	seen map[uint64]bool
+	path []Move // Reversed
+}
+
+func (s *search) dfs(g *game.Game) bool {
+	// Forced moves first, without branching
+	var forced []Move
+	for {
+		m,ok := safeMove(g)
+		if !ok {
+			break
+		}
+		if err := Apply(g, m); err != nil {
+			panic(err)
+		}
+		forced = append(forced, m)
+	}
+	won := s.search(g)
+	if won {
+		for i := len(forced) - 1; i >= 0; i-- {
+			s.path = append(s.path, forced[i])
+		}
+	}
+	return won
+}
+
+func (s *search) search(g *game.Game) bool {
+	if g.IsWon() {
+		return true
+	}
+	key := hash(g)
+	if s.seen[key] || s.nodes >= s.maxNodes {
+		return false
+	}
+	s.seen[key] = true
+	s.nodes++
+
+	for _,m := range moves(g) {
+		child := g.Clone()
+		if err := Apply(child, m); err != nil {
+			panic(fmt.Sprintf("solver generated illegal move %v: %v", m, err))
+		}
+		if s.dfs(child) {
+			s.path = append(s.path, m)
+			return true
+		}
+		if s.nodes >= s.maxNodes {
+			return false
+		}
+	}
+	return false
+}
+
+// A move to the foundation that can't be a mistake: an ace or a two, or a card neither
+// of whose possible children (the cards one lower of the other colour) can need it any
+// more, because they're up too, along with what they could need.
+func safeMove(g *game.Game) (Move,bool) {
+	safe := func(c deck.Card) bool {
+		if canPush,_ := g.CanPushSuit(c); !canPush {
+			return false
+		}
+		r := int(c.Rank)
+		if r <= int(deck.Two) {
+			return true
+		}
+		for suit,size := range g.SuitStacks {
+			if deck.SuitT(suit) == c.Suit {
+				continue
+			}
+			sameColor := byte(suit) % 2 == c.Color()
+			if (!sameColor && size < r) || (sameColor && size < r - 1) {
+				return false
+			}
+		}
+		return true
+	}
+	for i := range game.NStacks {
+		if c,ok := front(g, i); ok && safe(c) {
+			return Move{Kind: agent.ToTop, Src: i, Dst: -1},true
+		}
+	}
+	if len(g.Avail) > 0 && safe(g.Avail[len(g.Avail) - 1]) {
+		return Move{Kind: agent.AvailToTop, Src: -1, Dst: -1},true
+	}
+	return Move{},false
+}
+
+// Legal moves, most promising first
+func moves(g *game.Game) []Move {
+	var toTop, reveal, fromAvail, other, fromTop []Move
+
+	for i := range game.NStacks {
+		if c,ok := front(g, i); ok {
+			if canPush,_ := g.CanPushSuit(c); canPush {
+				toTop = append(toTop, Move{Kind: agent.ToTop, Src: i, Dst: -1})
+			}
+		}
+	}
+	if len(g.Avail) > 0 {
+		c := g.Avail[len(g.Avail) - 1]
+		if canPush,_ := g.CanPushSuit(c); canPush {
+			toTop = append(toTop, Move{Kind: agent.AvailToTop, Src: -1, Dst: -1})
+		}
+		for dst := range game.NStacks {
+			if fits(g, c, dst) {
+				fromAvail = append(fromAvail, Move{Kind: agent.AvailToTableau, Src: -1, Dst: dst})
+			}
+		}
+	}
+
+	for src := range game.NStacks {
+		queue := g.VisibleQueues[src]
+		for n := 1; n <= len(queue); n++ {
+			whole := n == len(queue)
+			nHidden := len(g.HiddenStacks[src])
+			for dst := range game.NStacks {
+				if dst == src || !fits(g, queue[n-1], dst) {
+					continue
+				}
+				dstEmpty := len(g.VisibleQueues[dst]) == 0
+				switch {
+				case whole && nHidden == 0 && dstEmpty:
+					// A king from one empty column to another
+				case whole && nHidden > 0:
+					reveal = append(reveal, Move{Kind: agent.Tableau, Src: src, Dst: dst, N: n})
+				case whole:
+					// Empties a column, which is only any use to a king
+					if kingWaiting(g) {
+						other = append(other, Move{Kind: agent.Tableau, Src: src, Dst: dst, N: n})
+					}
+				default:
+					// Only to get at the card underneath for the foundation
+					if canPush,_ := g.CanPushSuit(queue[n]); canPush {
+						other = append(other, Move{Kind: agent.Tableau, Src: src, Dst: dst, N: n})
+					}
+				}
+			}
+		}
+	}
+
+	for suit := range deck.NSuits {
+		if g.SuitStacks[suit] > 0 {
+			c := deck.NewCard(g.SuitStacks[suit] - 1, suit)
+			for dst := range game.NStacks {
+				if fits(g, c, dst) {
+					fromTop = append(fromTop, Move{Kind: agent.FromTop, Src: suit, Dst: dst})
+				}
+			}
+		}
+	}
+
+	// Dig where the most cards are face down first
+	slices.SortStableFunc(reveal, func(a, b Move) int {
+		return len(g.HiddenStacks[b.Src]) - len(g.HiddenStacks[a.Src])
+	})
+	all := append(toTop, reveal...)
+	all = append(all, fromAvail...)
+	all = append(all, stockMoves(g)...)
+	all = append(all, other...)
+	return append(all, fromTop...)
+}
+
+// Moves of cards further into the stock: flip until the card is on top of the Avail,
+// then play it. Every card that can get there is tried once, at the fewest flips.
+func stockMoves(g *game.Game) []Move {
+	var ms []Move
+	stock := &game.Game{Rules: g.Rules, Deck: g.Deck, Avail: g.Avail, Passes: g.Passes}
+	stock = stock.Clone()
+	tried := make(map[deck.Card]bool)
+	if len(stock.Avail) > 0 {
+		tried[stock.Avail[len(stock.Avail) - 1]] = true // Already a move without flipping
+	}
+	// The rest of this pass and all of the next. After that the tops come round again.
+	nFlip := g.Rules.NFlip
+	total := len(g.Deck) + len(g.Avail)
+	maxFlips := (len(g.Deck) + nFlip - 1) / nFlip + (total + nFlip - 1) / nFlip
+	for flips := 1; flips <= maxFlips; flips++ {
+		if len(stock.Deck) == 0 && (len(stock.Avail) == 0 || !stock.CanRedeal()) {
+			break // Nothing more to turn up
+		}
+		stock.Flip()
+		if len(stock.Avail) == 0 {
+			continue
+		}
+		c := stock.Avail[len(stock.Avail) - 1]
+		if tried[c] {
+			continue
+		}
+		tried[c] = true
+		if canPush,_ := g.CanPushSuit(c); canPush {
+			ms = append(ms, Move{Kind: agent.AvailToTop, Src: -1, Dst: -1, Flips: flips})
+		}
+		for dst := range game.NStacks {
+			if fits(g, c, dst) {
+				ms = append(ms, Move{Kind: agent.AvailToTableau, Src: -1, Dst: dst, Flips: flips})
+			}
+		}
+	}
+	return ms
+}
+
+// Whether a king could still want an empty column: one that's anywhere but at the bottom
+// of a column, or on the foundation
+func kingWaiting(g *game.Game) bool {
+	for _,c := range g.Avail {
+		if c.Rank == deck.King {
+			return true
+		}
+	}
+	for _,c := range g.Deck {
+		if c.Rank == deck.King {
+			return true
+		}
+	}
+	for i := range game.NStacks {
+		for _,c := range g.HiddenStacks[i] {
+			if c.Rank == deck.King {
+				return true
+			}
+		}
+		queue := g.VisibleQueues[i]
+		for j,c := range queue {
+			if c.Rank == deck.King && (j < len(queue) - 1 || len(g.HiddenStacks[i]) > 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// g.PeekQueue, without building an error for empty stacks, which the search does a lot
+func front(g *game.Game, i int) (deck.Card,bool) {
+	if len(g.VisibleQueues[i]) == 0 {
+		return deck.Card{},false
+	}
+	return g.VisibleQueues[i][0],true
+}
+
+// Whether `c` can go on stack `dst`
+func fits(g *game.Game, c deck.Card, dst int) bool {
+	f,ok := front(g, dst)
+	if !ok {
+		return c.Rank == deck.King
+	}
+	return deck.CanPlace(c, f)
+}
+
+// FNV-1a over everything that can change. The face-down cards of a stack can only ever
+// be the ones it was dealt, so their number is enough.
+func hash(g *game.Game) uint64 {
+	h := uint64(14695981039346656037)
+	add := func(b byte) {
+		h ^= uint64(b)
+		h *= 1099511628211
+	}
+	addCards := func(cards []deck.Card) {
+		add(byte(len(cards)))
+		for _,c := range cards {
+			add(byte(c.Suit)*byte(deck.SuitSize) + byte(c.Rank))
+		}
+	}
+	for _,size := range g.SuitStacks {
+		add(byte(size))
+	}
+	for i := range game.NStacks {
+		add(byte(len(g.HiddenStacks[i])))
+		addCards(g.VisibleQueues[i])
+	}
+	addCards(g.Avail)
+	addCards(g.Deck)
+	if g.Rules.MaxPasses > 0 {
+		add(byte(g.Passes))
+	}
+	return h
+}
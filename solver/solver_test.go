@@ -0,0 +1,84 @@
+package solver
+
+import (
+	"testing"
+
+	"solitaire/agent"
+	"solitaire/deck"
+	"solitaire/game"
+	"solitaire/sim"
+)
+
+var testRules = []game.Rules{game.DefaultRules, {NFlip: 1}, {NFlip: 3, MaxPasses: 3}}
+
+// Every winning line the solver finds has to replay, move by move, to a won game
+func TestSolveReplays(t *testing.T) {
+	for _,rules := range testRules {
+		won := 0
+		for seed := int64(0); seed < 30; seed++ {
+			r := Solve(game.NewGameWithRules(sim.Deal(seed), rules), 20000)
+			if r.Status != Won {
+				continue
+			}
+			won++
+			g := game.NewGameWithRules(sim.Deal(seed), rules)
+			for i,m := range r.Moves {
+				if err := Apply(g, m); err != nil {
+					t.Fatalf("%v deal %v: move %v (%v) is illegal: %v", rules, seed, i+1, m, err)
+				}
+			}
+			if !g.IsWon() {
+				t.Errorf("%v deal %v: the line of %v moves doesn't win", rules, seed, len(r.Moves))
+			}
+		}
+		if won == 0 {
+			t.Errorf("%v: no deal won, so nothing was checked", rules)
+		}
+	}
+}
+
+// Taking the move safeMove offers never turns a won position into one that can't be won
+func TestSafeMoveKeepsWins(t *testing.T) {
+	checked := 0
+	for seed := int64(0); seed < 10; seed++ {
+		g := game.NewGame(sim.Deal(seed))
+		r := Solve(g, 20000)
+		if r.Status != Won {
+			continue
+		}
+		for _,m := range r.Moves {
+			if safe,ok := safeMove(g); ok {
+				after := g.Clone()
+				if err := Apply(after, safe); err != nil {
+					t.Fatalf("deal %v: safe move %v is illegal: %v", seed, safe, err)
+				}
+				if s := Solve(after, 200000).Status; s == Unwinnable {
+					t.Errorf("deal %v: safe move %v loses a won position", seed, safe)
+				}
+				checked++
+			}
+			if err := Apply(g, m); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if checked == 0 {
+		t.Error("no safe moves were checked")
+	}
+}
+
+func TestApplyRejectsBadIndices(t *testing.T) {
+	for _,m := range []Move{
+		{Kind: agent.Tableau, Src: 0, Dst: game.NStacks},
+		{Kind: agent.Tableau, Src: -1, Dst: 0},
+		{Kind: agent.Tableau, Src: 0, Dst: 1, N: -1},
+		{Kind: agent.AvailToTableau, Src: -1, Dst: 9},
+		{Kind: agent.ToTop, Src: game.NStacks, Dst: -1},
+		{Kind: agent.FromTop, Src: deck.NSuits, Dst: 0},
+		{Kind: agent.FromTop, Src: 0, Dst: -1},
+	} {
+		if err := Apply(game.NewGame(sim.Deal(0)), m); err == nil {
+			t.Errorf("%v: no error", m)
+		}
+	}
+}
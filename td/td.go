@@ -0,0 +1,88 @@
+package td
+
+import (
+	"fmt"
+	"math/rand"
+
+	"solitaire/agent"
+	"solitaire/game"
+	"solitaire/sim"
+)
+
+// TD(λ) training of an agent.ValueNet by self-play, with no discounting. The only reward
+// comes at the end of the game: the fraction of cards on the foundation, which is 1 for a
+// win. Rewarding wins alone is too sparse to learn from, since the untrained agent barely
+// ever wins.
+type Config struct {
+	Episodes int
+	FirstSeed int64 // Training deals are FirstSeed, FirstSeed+1, ...
+	Alpha float64 // Learning rate
+	Lambda float64
+	Epsilon float64 // Exploration while training
+	EvalEvery int // Episodes between evaluations, 0 for none
+	EvalSeeds []int64 // Deals for evaluation, should not overlap the training deals
+	Workers int // For evaluation only; training is sequential
+	Seed int64
+}
+
+// A point on the learning curve
+type Eval struct {
+	Episode int
+	WinRate float64
+}
+
+func Train(net *agent.ValueNet, cfg Config) []Eval {
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	grad := make([]float64, len(net.Weights))
+	trace := make([]float64, len(net.Weights))
+	strategy := agent.TDStrategy{Net: net, Epsilon: cfg.Epsilon}
+
+	var curve []Eval
+	for ep := range cfg.Episodes {
+		clear(trace)
+		g := game.NewGame(sim.Deal(cfg.FirstSeed + int64(ep)))
+		player,err := agent.NewAgent(g, strategy)
+		if err != nil {
+			panic(err)
+		}
+		player.Seed(rng.Int63())
+
+		v := net.ValueGrad(agent.ValueFeatures(g.View()), grad)
+		turnsWithoutMove := 0
+		for nMoves := 0; ; nMoves++ {
+			if player.Act(false) {
+				turnsWithoutMove = 0
+			} else {
+				turnsWithoutMove++
+			}
+			done := g.IsWon() || nMoves + 1 >= sim.MaxMoves ||
+				turnsWithoutMove >= max(len(g.Avail) + len(g.Deck), 10)
+
+			for i := range trace {
+				trace[i] = cfg.Lambda*trace[i] + grad[i]
+			}
+			features := agent.ValueFeatures(g.View())
+			var delta float64
+			if done {
+				delta = float64(g.View().NFoundation())/52 - v
+			} else {
+				delta = net.Value(features) - v
+			}
+			for i := range net.Weights {
+				net.Weights[i] += cfg.Alpha * delta * trace[i]
+			}
+			if done {
+				break
+			}
+			v = net.ValueGrad(features, grad)
+		}
+
+		if cfg.EvalEvery > 0 && (ep + 1) % cfg.EvalEvery == 0 {
+			greedy := agent.TDStrategy{Net: net}
+			e := Eval{Episode: ep + 1, WinRate: sim.WinRate(greedy, cfg.EvalSeeds, cfg.Workers)}
+			curve = append(curve, e)
+			fmt.Printf("Episode %v: win rate %.4f\n", e.Episode, e.WinRate)
+		}
+	}
+	return curve
+}
@@ -0,0 +1,393 @@
+package tune
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"solitaire/agent"
+	"solitaire/sim"
+)
+
+// A genetic search over the parameters of a Tunable strategy.
+//
+// Candidates are scored by win rate on a fixed set of training deals, so every candidate
+// (and every generation) sees exactly the same games. Each parameter is searched over
+// [0, 1], mapped linearly or logarithmically onto [Min, Max] (see agent.Param).
+type Config struct {
+	Base agent.Tunable // Parameters are searched for this strategy type; its values seed the search
+	TrainSeeds []int64
+	HoldoutSeeds []int64
+	Population int
+	Elite int // Number of candidates kept unchanged from one generation to the next
+	Generations int
+	Sigma float64 // Mutation standard deviation, in search space
+	Finalists int // Number of top candidates re-checked on the held-out deals
+	Workers int
+	Seed int64
+	OutDir string // Best parameters, learning curve and checkpoint are written here
+	Stop <-chan struct{} // Closing it stops the search at the next candidate, see ErrStopped
+}
+
+// Returned by Run when Config.Stop was closed. The checkpoint is up to date, so running
+// again with the same Config carries on.
+var ErrStopped = errors.New("tune: stopped")
+
+// Statistics for one generation, i.e. one point on the learning curve
+type Generation struct {
+	Index int
+	BestTrain float64
+	MeanTrain float64
+	Best []float64
+}
+
+type Candidate struct {
+	Genome []float64
+	TrainWinRate float64
+}
+
+// What decides a search's results. A checkpoint only carries on the search it came from.
+type Setup struct {
+	Strategy string // The base strategy, if it's a fmt.Stringer, or else its type
+	Params []string
+	Start []float64 // The base strategy's values
+	Train string // Number of seeds and their hash
+	Holdout string
+	Population int
+	Elite int
+	Generations int
+	Sigma float64
+	Seed int64
+}
+
+// Everything needed to resume a run after the last completed generation
+type Checkpoint struct {
+	Setup Setup
+	Generation int
+	Population []Candidate // To be scored in the next generation
+	Ranked []Candidate // The last generation, best first
+	Curve []Generation
+}
+
+type Result struct {
+	Params []string
+	Values []float64
+	TrainWinRate float64
+	HoldoutWinRate float64
+	Curve []Generation
+}
+
+const checkpointFile = "checkpoint.json"
+
+func (cfg Config) decode(genome []float64) []float64 {
+	params := cfg.Base.Params()
+	values := make([]float64, len(params))
+	for i,p := range params {
+		x := genome[i]
+		if p.Log {
+			lo, hi := math.Log10(p.Min), math.Log10(p.Max)
+			values[i] = math.Pow(10, lo + x*(hi - lo))
+		} else {
+			values[i] = p.Min + x*(p.Max - p.Min)
+		}
+	}
+	return values
+}
+
+func (cfg Config) encode(values []float64) []float64 {
+	params := cfg.Base.Params()
+	genome := make([]float64, len(params))
+	for i,p := range params {
+		var x float64
+		if p.Log {
+			lo, hi := math.Log10(p.Min), math.Log10(p.Max)
+			x = (math.Log10(max(values[i], p.Min)) - lo) / (hi - lo)
+		} else {
+			x = (values[i] - p.Min) / (p.Max - p.Min)
+		}
+		genome[i] = clamp(x)
+	}
+	return genome
+}
+
+func clamp(x float64) float64 {
+	return min(max(x, 0), 1)
+}
+
+func (cfg Config) winRate(genome []float64, seeds []int64) float64 {
+	strategy := cfg.Base.WithValues(cfg.decode(genome))
+	return sim.WinRate(strategy, seeds, cfg.Workers)
+}
+
+// The first population: the base strategy plus random candidates
+func (cfg Config) initialPopulation(rng *rand.Rand) []Candidate {
+	pop := make([]Candidate, cfg.Population)
+	pop[0].Genome = cfg.encode(cfg.Base.Values())
+	for i := 1; i < len(pop); i++ {
+		genome := make([]float64, len(pop[0].Genome))
+		for j := range genome {
+			genome[j] = rng.Float64()
+		}
+		pop[i].Genome = genome
+	}
+	return pop
+}
+
+// Keep the elite, fill the rest with mutated crossovers of elite parents
+func (cfg Config) nextPopulation(sorted []Candidate, rng *rand.Rand) []Candidate {
+	next := make([]Candidate, cfg.Population)
+	copy(next, sorted[:cfg.Elite])
+	for i := cfg.Elite; i < len(next); i++ {
+		a := sorted[rng.Intn(cfg.Elite)].Genome
+		b := sorted[rng.Intn(cfg.Elite)].Genome
+		child := make([]float64, len(a))
+		for j := range child {
+			if rng.Intn(2) == 0 {
+				child[j] = a[j]
+			} else {
+				child[j] = b[j]
+			}
+			child[j] = clamp(child[j] + cfg.Sigma*rng.NormFloat64())
+		}
+		next[i] = Candidate{Genome: child, TrainWinRate: math.NaN()}
+	}
+	return next
+}
+
+func (cfg Config) setup() Setup {
+	s := Setup{
+		Strategy: fmt.Sprintf("%T", cfg.Base),
+		Start: cfg.Base.Values(),
+		Train: seedsKey(cfg.TrainSeeds),
+		Holdout: seedsKey(cfg.HoldoutSeeds),
+		Population: cfg.Population,
+		Elite: cfg.Elite,
+		Generations: cfg.Generations,
+		Sigma: cfg.Sigma,
+		Seed: cfg.Seed,
+	}
+	if str,ok := cfg.Base.(fmt.Stringer); ok {
+		s.Strategy = str.String()
+	}
+	for _,p := range cfg.Base.Params() {
+		s.Params = append(s.Params, p.Name)
+	}
+	return s
+}
+
+func seedsKey(seeds []int64) string {
+	h := fnv.New64a()
+	for _,s := range seeds {
+		binary.Write(h, binary.LittleEndian, s)
+	}
+	return fmt.Sprintf("%v seeds, hash %x", len(seeds), h.Sum64())
+}
+
+func (s Setup) sameSearch(o Setup) error {
+	switch {
+	case s.Strategy != o.Strategy || !slices.Equal(s.Params, o.Params) || !slices.Equal(s.Start, o.Start):
+		return fmt.Errorf("strategy %v tuning %v from %v, not %v tuning %v from %v", s.Strategy, s.Params, s.Start, o.Strategy, o.Params, o.Start)
+	case s.Train != o.Train || s.Holdout != o.Holdout:
+		return fmt.Errorf("training deals %v and held-out %v, not %v and %v", s.Train, s.Holdout, o.Train, o.Holdout)
+	case s.Population != o.Population || s.Elite != o.Elite:
+		return fmt.Errorf("population %v with elite %v, not %v with %v", s.Population, s.Elite, o.Population, o.Elite)
+	case s.Generations != o.Generations || s.Sigma != o.Sigma || s.Seed != o.Seed:
+		return fmt.Errorf("%v generations with sigma %v and seed %v, not %v with %v and %v",
+			s.Generations, s.Sigma, s.Seed, o.Generations, o.Sigma, o.Seed)
+	}
+	return nil
+}
+
+func (cfg Config) validate() error {
+	if cfg.Base == nil {
+		return errors.New("tune: no base strategy")
+	}
+	if len(cfg.TrainSeeds) == 0 {
+		return errors.New("tune: no training deals")
+	}
+	if cfg.Generations < 1 {
+		return fmt.Errorf("tune: need at least 1 generation, got %v", cfg.Generations)
+	}
+	if cfg.Population < 2 || cfg.Elite < 1 || cfg.Elite > cfg.Population {
+		return fmt.Errorf("tune: need population >= 2 and 1 <= elite <= population, got %v and %v",
+			cfg.Population, cfg.Elite)
+	}
+	return nil
+}
+
+// Run (or resume) the search, writing a checkpoint after every generation.
+// Deterministic for a given Config: generation g draws from a source seeded with Seed+g,
+// so a resumed run ends exactly where an uninterrupted one would.
+func Run(cfg Config) (Result,error) {
+	if err := cfg.validate(); err != nil {
+		return Result{},err
+	}
+	if err := os.MkdirAll(cfg.OutDir, 0o755); err != nil {
+		return Result{},err
+	}
+
+	ckpt,err := LoadCheckpoint(filepath.Join(cfg.OutDir, checkpointFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		rng := rand.New(rand.NewSource(cfg.Seed))
+		ckpt = Checkpoint{Setup: cfg.setup(), Population: cfg.initialPopulation(rng)}
+		for i := range ckpt.Population {
+			ckpt.Population[i].TrainWinRate = math.NaN()
+		}
+	} else if err != nil {
+		return Result{},err
+	} else if err := ckpt.Setup.sameSearch(cfg.setup()); err != nil {
+		return Result{},fmt.Errorf("tune: %v holds a different search (%w), use another directory", cfg.OutDir, err)
+	} else {
+		fmt.Printf("Resuming from generation %v\n", ckpt.Generation)
+	}
+
+	for ckpt.Generation < cfg.Generations {
+		pop := ckpt.Population
+		for i := range pop {
+			if !math.IsNaN(pop[i].TrainWinRate) { // Elite keep their score
+				continue
+			}
+			select {
+			case <-cfg.Stop:
+				return Result{},ErrStopped
+			default:
+			}
+			pop[i].TrainWinRate = cfg.winRate(pop[i].Genome, cfg.TrainSeeds)
+			// Scores are saved as they come, so stopping loses at most one candidate's games
+			if err := SaveCheckpoint(filepath.Join(cfg.OutDir, checkpointFile), ckpt); err != nil {
+				return Result{},err
+			}
+		}
+		sort.SliceStable(pop, func(i, j int) bool { return pop[i].TrainWinRate > pop[j].TrainWinRate })
+
+		mean := 0.0
+		for _,c := range pop {
+			mean += c.TrainWinRate
+		}
+		mean /= float64(len(pop))
+		gen := Generation{
+			Index: ckpt.Generation,
+			BestTrain: pop[0].TrainWinRate,
+			MeanTrain: mean,
+			Best: cfg.decode(pop[0].Genome),
+		}
+		ckpt.Curve = append(ckpt.Curve, gen)
+		fmt.Printf("Generation %v: best %.4f mean %.4f %v\n", gen.Index, gen.BestTrain, gen.MeanTrain, gen.Best)
+
+		ckpt.Generation++
+		ckpt.Ranked = pop
+		rng := rand.New(rand.NewSource(cfg.Seed + int64(ckpt.Generation)))
+		ckpt.Population = cfg.nextPopulation(pop, rng)
+		if err := SaveCheckpoint(filepath.Join(cfg.OutDir, checkpointFile), ckpt); err != nil {
+			return Result{},err
+		}
+	}
+
+	result,err := cfg.pickOnHoldout(ckpt)
+	if err != nil {
+		return Result{},err
+	}
+	if err := writeResult(cfg.OutDir, result); err != nil {
+		return Result{},err
+	}
+	return result,nil
+}
+
+// Re-score the top candidates on the held-out deals and keep the best there,
+// since the training deals are what the search overfits to
+func (cfg Config) pickOnHoldout(ckpt Checkpoint) (Result,error) {
+	finalists := ckpt.Ranked[:min(max(cfg.Finalists, 1), len(ckpt.Ranked))]
+	if len(finalists) == 0 {
+		return Result{},errors.New("tune: no candidates were scored, so there are no finalists")
+	}
+	best := Result{HoldoutWinRate: -1}
+	for _,c := range finalists {
+		holdout := cfg.winRate(c.Genome, cfg.HoldoutSeeds)
+		if holdout > best.HoldoutWinRate {
+			best.Values = cfg.decode(c.Genome)
+			best.TrainWinRate = c.TrainWinRate
+			best.HoldoutWinRate = holdout
+		}
+	}
+	for _,p := range cfg.Base.Params() {
+		best.Params = append(best.Params, p.Name)
+	}
+	best.Curve = ckpt.Curve
+	return best,nil
+}
+
+func LoadCheckpoint(path string) (Checkpoint,error) {
+	var ckpt Checkpoint
+	data,err := os.ReadFile(path)
+	if err != nil {
+		return ckpt,err
+	}
+	err = json.Unmarshal(data, &ckpt)
+	for i,c := range ckpt.Population {
+		if c.TrainWinRate < 0 {
+			ckpt.Population[i].TrainWinRate = math.NaN()
+		}
+	}
+	return ckpt,err
+}
+
+// Written to a temporary file first, so an interrupted write never leaves a corrupt checkpoint
+func SaveCheckpoint(path string, ckpt Checkpoint) error {
+	// NaN is not valid JSON; unscored candidates are recognised by a negative win rate on disk
+	saved := ckpt
+	saved.Population = make([]Candidate, len(ckpt.Population))
+	for i,c := range ckpt.Population {
+		saved.Population[i] = c
+		if math.IsNaN(c.TrainWinRate) {
+			saved.Population[i].TrainWinRate = -1
+		}
+	}
+
+	data,err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func writeResult(dir string, result Result) error {
+	best := make(map[string]float64)
+	for i,name := range result.Params {
+		best[name] = result.Values[i]
+	}
+	data,err := json.MarshalIndent(map[string]any{
+		"params": best,
+		"train_win_rate": result.TrainWinRate,
+		"holdout_win_rate": result.HoldoutWinRate,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "best.json"), data, 0o644); err != nil {
+		return err
+	}
+
+	f,err := os.Create(filepath.Join(dir, "curve.csv"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Fprintln(f, "generation,best_train,mean_train")
+	for _,g := range result.Curve {
+		fmt.Fprintf(f, "%v,%v,%v\n", g.Index, g.BestTrain, g.MeanTrain)
+	}
+	return nil
+}